@@ -2,7 +2,6 @@
 package k3s
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"os"
@@ -10,9 +9,6 @@ import (
 	"strings"
 	"time"
 
-	dockerContainer "github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/client"
-	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"k8s.io/client-go/kubernetes"
@@ -25,12 +21,13 @@ import (
 const (
 	containerName = "k3s"
 	apiPort       = 6443
-
-	waitForReadyDelay = 2 * time.Second
 )
 
 // K3s represents a running K3s container for integration testing.
 type K3s interface {
+	// Lifecycle allows to stop, start, pause or kill the container.
+	docker.Lifecycle
+
 	// Close stops the container and cleans up the temp kubeconfig file.
 	Close(ctx context.Context) error
 
@@ -42,27 +39,27 @@ type K3s interface {
 
 	// ID returns the Docker container ID.
 	ID() docker.ContainerID
+
+	// Container returns the underlying container.
+	Container() docker.Container
 }
 
 type k3s struct {
+	docker.Lifecycle
+
 	c              docker.Container
-	dockerCli      *client.Client
 	kubeconfigPath string
 	kubeconfigData []byte
 }
 
 // New creates a new K3s container with the default image.
-func New(ctx context.Context) (K3s, error) {
-	return NewWithImage(ctx, images.K3s)
+func New(ctx context.Context, opts ...docker.ContainerOption) (K3s, error) {
+	return NewWithImage(ctx, images.K3s, opts...)
 }
 
-// NewWithImage creates a new K3s container with a custom image.
-func NewWithImage(ctx context.Context, image string) (K3s, error) {
-	dockerCli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
-	if err != nil {
-		return nil, errors.Wrap(err, "error creating Docker client")
-	}
-
+// NewWithImage creates a new K3s container with a custom image. Options are
+// applied on top of the ones required by K3s.
+func NewWithImage(ctx context.Context, image string, opts ...docker.ContainerOption) (K3s, error) {
 	log.WithFields(log.Fields{
 		"image": image,
 	}).Debug("creating k3s container")
@@ -81,12 +78,14 @@ func NewWithImage(ctx context.Context, image string) (K3s, error) {
 			StringVar("K3S_KUBECONFIG_MODE", "644"),
 		docker.NewPortBindings().
 			PortDNAT(docker.ProtoTCP, apiPort),
-		docker.WithPrivileged(),
-		docker.WithTmpfs(map[string]string{
-			"/run": "",
-			"/tmp": "",
-		}),
-		docker.WithBinds("/lib/modules:/lib/modules:ro"),
+		append([]docker.ContainerOption{
+			docker.WithPrivileged(),
+			docker.WithTmpfs(map[string]string{
+				"/run": "",
+				"/tmp": "",
+			}),
+			docker.WithBinds("/lib/modules:/lib/modules:ro"),
+		}, opts...)...,
 	)
 	if err != nil {
 		return nil, errors.Wrap(err, "error creating k3s container")
@@ -101,26 +100,25 @@ func NewWithImage(ctx context.Context, image string) (K3s, error) {
 		}
 	}()
 
+	// The kubeconfig is written a bit later than the node controller
+	// is synced so wait for it to appear as well.
+	c.SetWaitStrategy(docker.ForAll(
+		docker.ForLog(docker.NewSubstringMatcher("Node controller sync successful")),
+		docker.ForExec("test", "-s", "/etc/rancher/k3s/k3s.yaml"),
+	))
+
 	if err := c.Run(ctx); err != nil {
 		return nil, errors.Wrap(err, "error running k3s container")
 	}
 
-	log.Trace("waiting for k3s readiness: Node controller sync successful")
-	if err := c.AwaitOutput(ctx, docker.NewSubstringMatcher("Node controller sync successful")); err != nil {
-		return nil, errors.Wrap(err, "error waiting for k3s readiness")
-	}
-
-	// Give k3s a moment to fully initialize and write the kubeconfig.
-	time.Sleep(waitForReadyDelay)
-
-	kubeconfigData, err := execReadFile(ctx, dockerCli, c.ID(), "/etc/rancher/k3s/k3s.yaml")
+	kubeconfigData, err := execReadFile(ctx, c, "/etc/rancher/k3s/k3s.yaml")
 	if err != nil {
 		return nil, errors.Wrap(err, "error retrieving kubeconfig from k3s container")
 	}
 
 	k := &k3s{
+		Lifecycle:      c,
 		c:              c,
-		dockerCli:      dockerCli,
 		kubeconfigData: kubeconfigData,
 	}
 
@@ -132,6 +130,11 @@ func NewWithImage(ctx context.Context, image string) (K3s, error) {
 	return k, nil
 }
 
+// Container returns the underlying container.
+func (k *k3s) Container() docker.Container {
+	return k.c
+}
+
 // Close stops the container and cleans up the temp kubeconfig file.
 func (k *k3s) Close(ctx context.Context) error {
 	if k.kubeconfigPath != "" {
@@ -141,10 +144,6 @@ func (k *k3s) Close(ctx context.Context) error {
 		}
 	}
 
-	if k.dockerCli != nil {
-		defer func() { _ = k.dockerCli.Close() }()
-	}
-
 	return k.c.Close(ctx)
 }
 
@@ -203,51 +202,21 @@ func (k *k3s) rewriteKubeconfig(ctx context.Context) error {
 }
 
 // execReadFile reads a file from inside a container using Docker exec.
-func execReadFile(ctx context.Context, cli *client.Client, containerID, path string) ([]byte, error) {
-	return execInContainer(ctx, cli, containerID, "cat", path)
+func execReadFile(ctx context.Context, c docker.Container, path string) ([]byte, error) {
+	return execInContainer(ctx, c, "cat", path)
 }
 
 // execInContainer runs an arbitrary command inside a container and returns its stdout.
-func execInContainer(ctx context.Context, cli *client.Client, containerID, cmd string, args ...string) ([]byte, error) {
-	log.WithFields(log.Fields{
-		"container": containerID,
-		"cmd":       cmd,
-		"args":      args,
-	}).Trace("executing command in container via exec")
-
-	execConfig := dockerContainer.ExecOptions{
-		Cmd:          append([]string{cmd}, args...),
-		AttachStdout: true,
-		AttachStderr: true,
-	}
-
-	execResp, err := cli.ContainerExecCreate(ctx, containerID, execConfig)
+func execInContainer(ctx context.Context, c docker.Container, cmd string, args ...string) ([]byte, error) {
+	res, err := c.Exec(ctx, append([]string{cmd}, args...), nil)
 	if err != nil {
-		return nil, errors.Wrap(err, "error creating exec instance")
+		return nil, err
 	}
 
-	attachResp, err := cli.ContainerExecAttach(ctx, execResp.ID, dockerContainer.ExecAttachOptions{})
-	if err != nil {
-		return nil, errors.Wrap(err, "error attaching to exec")
-	}
-	defer attachResp.Close()
-
-	// Docker multiplexes stdout and stderr into a single stream with headers.
-	// Use stdcopy to demultiplex and capture only stdout.
-	var stdoutBuf, stderrBuf bytes.Buffer
-	_, err = stdcopy.StdCopy(&stdoutBuf, &stderrBuf, attachResp.Reader)
-	if err != nil {
-		return nil, errors.Wrap(err, "error demuxing exec output")
-	}
-
-	inspectResp, err := cli.ContainerExecInspect(ctx, execResp.ID)
-	if err != nil {
-		return nil, errors.Wrap(err, "error inspecting exec result")
-	}
-	if inspectResp.ExitCode != 0 {
+	if res.ExitCode != 0 {
 		return nil, errors.Errorf("exec command exited with code %d: %s",
-			inspectResp.ExitCode, stderrBuf.String())
+			res.ExitCode, string(res.Stderr))
 	}
 
-	return stdoutBuf.Bytes(), nil
+	return res.Stdout, nil
 }
@@ -8,7 +8,6 @@ import (
 	"testing"
 	"time"
 
-	"github.com/docker/docker/client"
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/suite"
 	appsv1 "k8s.io/api/apps/v1"
@@ -39,7 +38,6 @@ type k3sTestSuite struct {
 	cancelFunc context.CancelFunc
 	app        K3s
 	clientset  *kubernetes.Clientset
-	dockerCli  *client.Client
 }
 
 func (s *k3sTestSuite) SetupSuite() {
@@ -51,9 +49,6 @@ func (s *k3sTestSuite) SetupSuite() {
 	s.clientset, err = s.app.Clientset(s.ctx)
 	s.Require().NoError(err)
 	s.Require().NotNil(s.clientset)
-
-	s.dockerCli, err = client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
-	s.Require().NoError(err)
 }
 
 func (s *k3sTestSuite) TearDownSuite() {
@@ -61,10 +56,6 @@ func (s *k3sTestSuite) TearDownSuite() {
 	defer cancel()
 	defer s.cancelFunc()
 
-	if s.dockerCli != nil {
-		_ = s.dockerCli.Close()
-	}
-
 	err := s.app.Close(ctx)
 	s.Require().NoError(err)
 }
@@ -253,7 +244,7 @@ func (s *k3sTestSuite) TestLoadBalancerService() {
 	// from the host. Instead, we verify by curling the service from inside
 	// the k3s container using Docker exec.
 	svcURL := fmt.Sprintf("http://%s:%d/", lbIngressHost, echoPort)
-	output, err := execInContainer(s.ctx, s.dockerCli, s.app.ID(),
+	output, err := execInContainer(s.ctx, s.app.(*k3s).c,
 		"wget", "-qO-", "--timeout=10", svcURL)
 	r.NoError(err, "should be able to reach the service from inside the container")
 
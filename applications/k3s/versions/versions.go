@@ -8,6 +8,7 @@ import (
 	"github.com/stretchr/testify/suite"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	"github.com/teran/go-docker-testsuite"
 	"github.com/teran/go-docker-testsuite/applications/k3s"
 )
 
@@ -29,13 +30,9 @@ func New(ctx context.Context, image string) *testSuite {
 // TestK3sVersion verifies that the K3s container starts and the Kubernetes
 // server version matches the expected minor version derived from the image tag.
 func (s *testSuite) TestK3sVersion() {
-	app, err := k3s.NewWithImage(s.ctx, s.image)
-	s.Require().NoError(err)
-
-	defer func() {
-		err := app.Close(s.ctx)
-		s.Require().NoError(err)
-	}()
+	app := docker.Start(s.T(), func() (k3s.K3s, error) {
+		return k3s.NewWithImage(s.ctx, s.image)
+	})
 
 	cs, err := app.Clientset(s.ctx)
 	s.Require().NoError(err)
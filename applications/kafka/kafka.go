@@ -15,20 +15,25 @@ const (
 )
 
 type Kafka interface {
+	docker.Lifecycle
+
 	Close(context.Context) error
 	GetBrokerURL(ctx context.Context) (string, error)
 	GetAdminURL(ctx context.Context) (string, error)
+	Container() docker.Container
 }
 
 type kafka struct {
+	docker.Lifecycle
+
 	c docker.Container
 }
 
-func New(ctx context.Context) (Kafka, error) {
-	return NewWithImage(ctx, images.Kafka)
+func New(ctx context.Context, opts ...docker.ContainerOption) (Kafka, error) {
+	return NewWithImage(ctx, images.Kafka, opts...)
 }
 
-func NewWithImage(ctx context.Context, image string) (Kafka, error) {
+func NewWithImage(ctx context.Context, image string, opts ...docker.ContainerOption) (Kafka, error) {
 	c, err := docker.NewContainer(
 		"kafka",
 		image,
@@ -90,6 +95,7 @@ func NewWithImage(ctx context.Context, image string) (Kafka, error) {
 		docker.NewDirectPortBinding().
 			PortDNAT(docker.ProtoTCP, brokerPort).
 			PortDNAT(docker.ProtoTCP, adminPort),
+		opts...,
 	)
 	if err != nil {
 		return nil, err
@@ -104,19 +110,17 @@ func NewWithImage(ctx context.Context, image string) (Kafka, error) {
 		}
 	}()
 
-	err = c.Run(ctx)
-	if err != nil {
-		return nil, err
-	}
+	c.SetWaitStrategy(docker.ForLog(docker.NewSubstringMatcher("] Kafka Server started (")))
 
-	err = c.AwaitOutput(ctx, docker.NewSubstringMatcher("] Kafka Server started ("))
+	err = c.Run(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	started = true
 	return &kafka{
-		c: c,
+		Lifecycle: c,
+		c:         c,
 	}, nil
 }
 
@@ -138,6 +142,11 @@ func (k *kafka) GetAdminURL(ctx context.Context) (string, error) {
 	return hp.String(), nil
 }
 
+// Container returns the underlying container
+func (k *kafka) Container() docker.Container {
+	return k.c
+}
+
 func (k *kafka) Close(ctx context.Context) error {
 	return k.c.Close(ctx)
 }
@@ -6,27 +6,30 @@ import (
 	"time"
 
 	memcacheCli "github.com/bradfitz/gomemcache/memcache"
-	"github.com/pkg/errors"
-	log "github.com/sirupsen/logrus"
 
 	docker "github.com/teran/go-docker-testsuite"
 	"github.com/teran/go-docker-testsuite/images"
 )
 
 type Memcache interface {
+	docker.Lifecycle
+
 	Close(context.Context) error
 	GetEndpointAddress() (string, error)
+	Container() docker.Container
 }
 
 type memcache struct {
+	docker.Lifecycle
+
 	c docker.Container
 }
 
-func New(ctx context.Context) (Memcache, error) {
-	return NewWithImage(ctx, images.Memcache)
+func New(ctx context.Context, opts ...docker.ContainerOption) (Memcache, error) {
+	return NewWithImage(ctx, images.Memcache, opts...)
 }
 
-func NewWithImage(ctx context.Context, image string) (Memcache, error) {
+func NewWithImage(ctx context.Context, image string, opts ...docker.ContainerOption) (Memcache, error) {
 	c, err := docker.NewContainer(
 		"memcache",
 		image,
@@ -34,6 +37,7 @@ func NewWithImage(ctx context.Context, image string) (Memcache, error) {
 		docker.NewEnvironment(),
 		docker.NewPortBindings().
 			PortDNAT(docker.ProtoTCP, 11211),
+		opts...,
 	)
 	if err != nil {
 		return nil, err
@@ -48,35 +52,32 @@ func NewWithImage(ctx context.Context, image string) (Memcache, error) {
 		}
 	}()
 
+	c.SetWaitStrategy(docker.ForCheck(func(ctx context.Context, c docker.Container) error {
+		hp, err := c.URL(docker.ProtoTCP, 11211)
+		if err != nil {
+			return err
+		}
+
+		cli := memcacheCli.New(fmt.Sprintf("%s:%d", hp.Host, hp.Port))
+		defer func() { _ = cli.Close() }()
+
+		return cli.Ping()
+	}))
+
 	if err := c.Run(ctx); err != nil {
 		return nil, err
 	}
 
-	hp, err := c.URL(docker.ProtoTCP, 11211)
-	if err != nil {
-		return nil, err
-	}
-	cli := memcacheCli.New(fmt.Sprintf("%s:%d", hp.Host, hp.Port))
-	defer func() { _ = cli.Close() }()
-
-	for i := 0; i < 30; i++ {
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		case <-time.After(500 * time.Millisecond):
-			if err := cli.Ping(); err != nil {
-				log.Tracef("memcached is not ready yet, let's wait a bit ...")
-				continue
-			}
-
-			started = true
-			return &memcache{
-				c: c,
-			}, nil
-		}
-	}
+	started = true
+	return &memcache{
+		Lifecycle: c,
+		c:         c,
+	}, nil
+}
 
-	return nil, errors.New("memcached did not become ready in time")
+// Container returns the underlying container
+func (m *memcache) Container() docker.Container {
+	return m.c
 }
 
 func (m *memcache) Close(ctx context.Context) error {
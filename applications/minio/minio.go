@@ -18,20 +18,25 @@ const (
 )
 
 type Minio interface {
+	docker.Lifecycle
+
 	Close(context.Context) error
 	GetEndpointURL() (string, error)
 	GetConsoleURL() (string, error)
+	Container() docker.Container
 }
 
 type minio struct {
+	docker.Lifecycle
+
 	c docker.Container
 }
 
-func New(ctx context.Context) (Minio, error) {
-	return NewWithImage(ctx, images.Minio)
+func New(ctx context.Context, opts ...docker.ContainerOption) (Minio, error) {
+	return NewWithImage(ctx, images.Minio, opts...)
 }
 
-func NewWithImage(ctx context.Context, image string) (Minio, error) {
+func NewWithImage(ctx context.Context, image string, opts ...docker.ContainerOption) (Minio, error) {
 	c, err := docker.
 		NewContainer(
 			"minio",
@@ -48,6 +53,7 @@ func NewWithImage(ctx context.Context, image string) (Minio, error) {
 			docker.NewPortBindings().
 				PortDNAT(docker.ProtoTCP, tcpPortS3).
 				PortDNAT(docker.ProtoTCP, tcpPortConsole),
+			opts...,
 		)
 	if err != nil {
 		return nil, err
@@ -62,21 +68,19 @@ func NewWithImage(ctx context.Context, image string) (Minio, error) {
 		}
 	}()
 
-	err = c.Run(ctx)
-	if err != nil {
-		return nil, err
-	}
-
-	err = c.AwaitOutput(ctx, docker.NewSubstringMatcher(
+	c.SetWaitStrategy(docker.ForLog(docker.NewSubstringMatcher(
 		"The standard parity is set to 0. This can lead to data loss.",
-	))
+	)))
+
+	err = c.Run(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	started = true
 	return &minio{
-		c: c,
+		Lifecycle: c,
+		c:         c,
 	}, nil
 }
 
@@ -96,6 +100,11 @@ func (m *minio) GetConsoleURL() (string, error) {
 	return fmt.Sprintf("%s:%d", hp.Host, hp.Port), nil
 }
 
+// Container returns the underlying container
+func (m *minio) Container() docker.Container {
+	return m.c
+}
+
 func (m *minio) Close(ctx context.Context) error {
 	return m.c.Close(ctx)
 }
@@ -49,18 +49,23 @@ func quoteMySQLIdentifier(name string) string {
 }
 
 type MySQL interface {
+	docker.Lifecycle
+
 	Close(ctx context.Context) error
 	CreateDB(ctx context.Context, name string) error
 	DropDB(ctx context.Context, name string) error
 	DSN(name string) (string, error)
 	MustDSN(name string) string
+	Container() docker.Container
 }
 
 type mysql struct {
+	docker.Lifecycle
+
 	c docker.Container
 }
 
-func New(ctx context.Context, image string) (MySQL, error) {
+func New(ctx context.Context, image string, opts ...docker.ContainerOption) (MySQL, error) {
 	c, err := docker.NewContainer(
 		"mysql",
 		image,
@@ -71,13 +76,15 @@ func New(ctx context.Context, image string) (MySQL, error) {
 		docker.
 			NewPortBindings().
 			PortDNAT(docker.ProtoTCP, 3306),
+		opts...,
 	)
 	if err != nil {
 		return nil, errors.Wrap(err, "error creating new container")
 	}
 
 	app := &mysql{
-		c: c,
+		Lifecycle: c,
+		c:         c,
 	}
 
 	started := false
@@ -89,10 +96,6 @@ func New(ctx context.Context, image string) (MySQL, error) {
 		}
 	}()
 
-	if err := c.Run(ctx); err != nil {
-		return nil, errors.Wrap(err, "error running container")
-	}
-
 	re, err := regexp.Compile(
 		`(mysqld|mariadbd):\s+ready\s+for\s+connections\.`,
 	)
@@ -100,29 +103,15 @@ func New(ctx context.Context, image string) (MySQL, error) {
 		return nil, errors.Wrap(err, "error compiling regex")
 	}
 
-	if err := c.AwaitOutput(ctx, docker.NewRegexpMatcher(re)); err != nil {
-		return nil, errors.Wrap(err, "error awaiting container output")
-	}
-
-	dsn, err := app.DSN("")
-	if err != nil {
-		return nil, errors.Wrap(err, "error obtaining database DSN")
-	}
-
-	db, err := sql.Open("mysql", dsn)
-	if err != nil {
-		return nil, errors.Wrap(err, "error opening database connection")
-	}
-	defer func() { _ = db.Close() }()
-
-	for i := 0; i < 30; i++ {
-		if err := db.Ping(); err == nil {
-			break
-		}
-
-		log.Debug("Database is not ready yet. Awaiting for ping to pass ...")
+	c.SetWaitStrategy(docker.ForAll(
+		docker.ForLog(docker.NewRegexpMatcher(re)),
+		docker.ForSQL("mysql", 3306, func(hp *docker.HostPort) string {
+			return fmt.Sprintf("root@tcp(%s:%d)/", hp.Host, hp.Port)
+		}),
+	))
 
-		time.Sleep(1 * time.Second)
+	if err := c.Run(ctx); err != nil {
+		return nil, errors.Wrap(err, "error running container")
 	}
 
 	started = true
@@ -198,6 +187,11 @@ func (m *mysql) MustDSN(name string) string {
 	return dsn
 }
 
+// Container returns the underlying container
+func (m *mysql) Container() docker.Container {
+	return m.c
+}
+
 func (m *mysql) Close(ctx context.Context) error {
 	return errors.Wrap(m.c.Close(ctx), "error closing database connection")
 }
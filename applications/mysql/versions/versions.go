@@ -5,6 +5,7 @@ import (
 
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/suite"
+	"github.com/teran/go-docker-testsuite"
 	"github.com/teran/go-docker-testsuite/applications/mysql"
 )
 
@@ -27,15 +28,11 @@ func New(ctx context.Context, image string) *testSuite {
 }
 
 func (s *testSuite) TestAll() {
-	ms, err := mysql.New(s.ctx, s.image)
-	s.Require().NoError(err)
-
-	defer func() {
-		err := ms.Close(s.ctx)
-		s.Require().NoError(err)
-	}()
+	ms := docker.Start(s.T(), func() (mysql.MySQL, error) {
+		return mysql.New(s.ctx, s.image)
+	})
 
-	err = ms.CreateDB(s.ctx, "somedb")
+	err := ms.CreateDB(s.ctx, "somedb")
 	s.Require().NoError(err)
 
 	err = ms.DropDB(s.ctx, "anotherdb")
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	pgx "github.com/jackc/pgx/v4"
+	_ "github.com/jackc/pgx/v4/stdlib"
 
 	"github.com/pkg/errors"
 	"github.com/teran/go-docker-testsuite"
@@ -49,22 +50,27 @@ func quotePGIdentifier(name string) string {
 }
 
 type PostgreSQL interface {
+	docker.Lifecycle
+
 	DSN(db string) (string, error)
 	MustDSN(db string) string
 	CreateDB(ctx context.Context, db string) error
 	DropDB(ctx context.Context, db string) error
 	Close(ctx context.Context) error
+	Container() docker.Container
 }
 
 type postgresql struct {
+	docker.Lifecycle
+
 	c docker.Container
 }
 
-func New(ctx context.Context) (PostgreSQL, error) {
-	return NewWithImage(ctx, images.Postgres)
+func New(ctx context.Context, opts ...docker.ContainerOption) (PostgreSQL, error) {
+	return NewWithImage(ctx, images.Postgres, opts...)
 }
 
-func NewWithImage(ctx context.Context, image string) (PostgreSQL, error) {
+func NewWithImage(ctx context.Context, image string, opts ...docker.ContainerOption) (PostgreSQL, error) {
 	c, err := docker.
 		NewContainer(
 			"postgres",
@@ -76,6 +82,7 @@ func NewWithImage(ctx context.Context, image string) (PostgreSQL, error) {
 			docker.
 				NewPortBindings().
 				PortDNAT(docker.ProtoTCP, 5432),
+			opts...,
 		)
 	if err != nil {
 		return nil, err
@@ -90,42 +97,25 @@ func NewWithImage(ctx context.Context, image string) (PostgreSQL, error) {
 		}
 	}()
 
-	err = c.Run(ctx)
-	if err != nil {
-		return nil, err
-	}
-
-	err = c.AwaitOutput(ctx, docker.NewSubstringMatcher("database system is ready to accept connections"))
-	if err != nil {
-		return nil, err
-	}
+	// Wait for PostgreSQL to accept TCP connections after the log line
+	// since the first one is printed by the temporary server started for
+	// the database initialization.
+	c.SetWaitStrategy(docker.ForAll(
+		docker.ForLog(docker.NewSubstringMatcher("database system is ready to accept connections")),
+		docker.ForSQL("pgx", 5432, func(hp *docker.HostPort) string {
+			return fmt.Sprintf("postgres://postgres@%s/%s?sslmode=disable", hp.String(), "postgres")
+		}),
+	))
 
-	hp, err := c.URL(docker.ProtoTCP, 5432)
+	err = c.Run(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	dsn := fmt.Sprintf("postgres://postgres@%s/%s?sslmode=disable", hp.String(), "postgres")
-
-	// Wait for PostgreSQL to accept TCP connections with a retry loop
-	// instead of a blind sleep, so startup is fast on fast hosts and
-	// resilient on loaded ones.
-	for i := 0; i < 30; i++ {
-		pgconn, pgErr := pgx.Connect(ctx, dsn)
-		if pgErr == nil {
-			_ = pgconn.Close(ctx)
-			break
-		}
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		case <-time.After(1 * time.Second):
-		}
-	}
-
 	started = true
 	return &postgresql{
-		c: c,
+		Lifecycle: c,
+		c:         c,
 	}, nil
 }
 
@@ -192,6 +182,11 @@ func (p *postgresql) MustDSN(db string) string {
 	return dsn
 }
 
+// Container returns the underlying container
+func (p *postgresql) Container() docker.Container {
+	return p.c
+}
+
 func (p *postgresql) Close(ctx context.Context) error {
 	return p.c.Close(ctx)
 }
@@ -5,6 +5,7 @@ import (
 
 	"github.com/stretchr/testify/suite"
 
+	"github.com/teran/go-docker-testsuite"
 	"github.com/teran/go-docker-testsuite/applications/postgres"
 )
 
@@ -23,15 +24,11 @@ func New(ctx context.Context, image string) *testSuite {
 }
 
 func (s *testSuite) TestAll() {
-	pg, err := postgres.NewWithImage(s.ctx, s.image)
-	s.Require().NoError(err)
-
-	defer func() {
-		err := pg.Close(s.ctx)
-		s.Require().NoError(err)
-	}()
+	pg := docker.Start(s.T(), func() (postgres.PostgreSQL, error) {
+		return postgres.NewWithImage(s.ctx, s.image)
+	})
 
-	err = pg.CreateDB(s.ctx, "somedb")
+	err := pg.CreateDB(s.ctx, "somedb")
 	s.Require().NoError(err)
 
 	err = pg.DropDB(s.ctx, "anotherdb")
@@ -23,6 +23,8 @@ const (
 )
 
 type RabbitMQ interface {
+	docker.Lifecycle
+
 	Close(ctx context.Context) error
 
 	GetAMQPURL(ctx context.Context) (string, error)
@@ -31,17 +33,20 @@ type RabbitMQ interface {
 	CreateVHost(ctx context.Context, name string) error
 	CreateUser(ctx context.Context, username, password string) error
 	SetPermissions(ctx context.Context, vhost, username, configure, write, read string) error
+	Container() docker.Container
 }
 
 type rabbitmq struct {
+	docker.Lifecycle
+
 	c docker.Container
 }
 
-func New(ctx context.Context) (RabbitMQ, error) {
-	return NewWithImage(ctx, images.RabbitMQ)
+func New(ctx context.Context, opts ...docker.ContainerOption) (RabbitMQ, error) {
+	return NewWithImage(ctx, images.RabbitMQ, opts...)
 }
 
-func NewWithImage(ctx context.Context, image string) (RabbitMQ, error) {
+func NewWithImage(ctx context.Context, image string, opts ...docker.ContainerOption) (RabbitMQ, error) {
 	c, err := docker.NewContainer(
 		"rabbitmq",
 		image,
@@ -52,6 +57,7 @@ func NewWithImage(ctx context.Context, image string) (RabbitMQ, error) {
 		docker.NewPortBindings().
 			PortDNAT(docker.ProtoTCP, amqpPort).
 			PortDNAT(docker.ProtoTCP, managementPort),
+		opts...,
 	)
 	if err != nil {
 		return nil, err
@@ -66,22 +72,28 @@ func NewWithImage(ctx context.Context, image string) (RabbitMQ, error) {
 		}
 	}()
 
-	if err := c.Run(ctx); err != nil {
-		return nil, err
-	}
+	c.SetWaitStrategy(docker.ForAll(
+		docker.ForLog(docker.NewSubstringMatcher("Server startup complete")),
+		docker.ForHTTP(managementPort, "/api/overview").
+			WithBasicAuth(defaultUser, defaultPassword),
+	))
 
-	if err := c.AwaitOutput(ctx, docker.NewSubstringMatcher("Server startup complete")); err != nil {
+	if err := c.Run(ctx); err != nil {
 		return nil, err
 	}
 
-	time.Sleep(1 * time.Second)
-
 	started = true
 	return &rabbitmq{
-		c: c,
+		Lifecycle: c,
+		c:         c,
 	}, nil
 }
 
+// Container returns the underlying container
+func (r *rabbitmq) Container() docker.Container {
+	return r.c
+}
+
 func (r *rabbitmq) Close(ctx context.Context) error {
 	return r.c.Close(ctx)
 }
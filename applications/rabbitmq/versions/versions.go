@@ -10,6 +10,7 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/suite"
 
+	"github.com/teran/go-docker-testsuite"
 	"github.com/teran/go-docker-testsuite/applications/rabbitmq"
 )
 
@@ -32,12 +33,9 @@ func New(ctx context.Context, image string) *testSuite {
 }
 
 func (s *testSuite) TestAMQP() {
-	app, err := rabbitmq.NewWithImage(s.ctx, s.image)
-	s.Require().NoError(err)
-	defer func() {
-		err := app.Close(s.ctx)
-		s.Require().NoError(err)
-	}()
+	app := docker.Start(s.T(), func() (rabbitmq.RabbitMQ, error) {
+		return rabbitmq.NewWithImage(s.ctx, s.image)
+	})
 
 	amqpURL, err := app.GetAMQPURL(s.ctx)
 	s.Require().NoError(err)
@@ -71,14 +69,11 @@ func (s *testSuite) TestAMQP() {
 }
 
 func (s *testSuite) TestManagementAPI() {
-	app, err := rabbitmq.NewWithImage(s.ctx, s.image)
-	s.Require().NoError(err)
-	defer func() {
-		err := app.Close(s.ctx)
-		s.Require().NoError(err)
-	}()
+	app := docker.Start(s.T(), func() (rabbitmq.RabbitMQ, error) {
+		return rabbitmq.NewWithImage(s.ctx, s.image)
+	})
 
-	err = app.CreateVHost(s.ctx, "/version-test-vhost")
+	err := app.CreateVHost(s.ctx, "/version-test-vhost")
 	s.Require().NoError(err)
 
 	err = app.CreateUser(s.ctx, "version-user", "version-pass")
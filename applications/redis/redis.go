@@ -8,16 +8,21 @@ import (
 )
 
 type Redis interface {
+	docker.Lifecycle
+
 	Addr() (string, error)
 	MustAddr() string
 	Close(ctx context.Context) error
+	Container() docker.Container
 }
 
 type redis struct {
+	docker.Lifecycle
+
 	c docker.Container
 }
 
-func New(ctx context.Context, image string) (Redis, error) {
+func New(ctx context.Context, image string, opts ...docker.ContainerOption) (Redis, error) {
 	c, err := docker.
 		NewContainer(
 			"redis",
@@ -27,6 +32,7 @@ func New(ctx context.Context, image string) (Redis, error) {
 			docker.
 				NewPortBindings().
 				PortDNAT(docker.ProtoTCP, 6379),
+			opts...,
 		)
 	if err != nil {
 		return nil, err
@@ -41,19 +47,17 @@ func New(ctx context.Context, image string) (Redis, error) {
 		}
 	}()
 
-	err = c.Run(ctx)
-	if err != nil {
-		return nil, err
-	}
+	c.SetWaitStrategy(docker.ForLog(docker.NewSubstringMatcher("* Ready to accept connections")))
 
-	err = c.AwaitOutput(ctx, docker.NewSubstringMatcher("* Ready to accept connections"))
+	err = c.Run(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	started = true
 	return &redis{
-		c: c,
+		Lifecycle: c,
+		c:         c,
 	}, nil
 }
 
@@ -74,6 +78,11 @@ func (r *redis) MustAddr() string {
 	return u
 }
 
+// Container returns the underlying container
+func (r *redis) Container() docker.Container {
+	return r.c
+}
+
 func (r *redis) Close(ctx context.Context) error {
 	return r.c.Close(ctx)
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/teran/go-docker-testsuite"
 	"github.com/teran/go-docker-testsuite/applications/redis"
 
 	redisClient "github.com/go-redis/redis/v8"
@@ -46,12 +47,7 @@ func (s *RedisTestSuite) TestRedis() {
 }
 
 func (s *RedisTestSuite) SetupTest() {
-	var err error
-	s.app, err = redis.New(s.ctx, s.image)
-	s.Require().NoError(err)
-}
-
-func (s *RedisTestSuite) TearDownTest() {
-	err := s.app.Close(s.ctx)
-	s.Require().NoError(err)
+	s.app = docker.Start(s.T(), func() (redis.Redis, error) {
+		return redis.New(s.ctx, s.image)
+	})
 }
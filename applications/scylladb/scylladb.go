@@ -49,22 +49,27 @@ func quoteCQLIdentifier(name string) string {
 }
 
 type ScyllaDB interface {
+	docker.Lifecycle
+
 	ClusterConfig(keyspaceName string) (*gocql.ClusterConfig, error)
 	CreateKeyspace(name string) error
 	DropKeyspace(name string) error
 	Close(context.Context) error
+	Container() docker.Container
 }
 
 type scylladb struct {
+	docker.Lifecycle
+
 	c       docker.Container
 	session *gocql.Session
 }
 
-func New(ctx context.Context) (ScyllaDB, error) {
-	return NewWithImage(ctx, images.ScyllaDB)
+func New(ctx context.Context, opts ...docker.ContainerOption) (ScyllaDB, error) {
+	return NewWithImage(ctx, images.ScyllaDB, opts...)
 }
 
-func NewWithImage(ctx context.Context, image string) (ScyllaDB, error) {
+func NewWithImage(ctx context.Context, image string, opts ...docker.ContainerOption) (ScyllaDB, error) {
 	c, err := docker.
 		NewContainer(
 			"scylladb",
@@ -80,6 +85,7 @@ func NewWithImage(ctx context.Context, image string) (ScyllaDB, error) {
 			docker.NewEnvironment(),
 			docker.NewPortBindings().
 				PortDNAT(docker.ProtoTCP, 9042),
+			opts...,
 		)
 	if err != nil {
 		return nil, err
@@ -94,11 +100,6 @@ func NewWithImage(ctx context.Context, image string) (ScyllaDB, error) {
 		}
 	}()
 
-	err = c.Run(ctx)
-	if err != nil {
-		return nil, err
-	}
-
 	ipRe := `\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}\`
 	l := fmt.Sprintf(
 		`\] cql_server_controller - Starting listening for CQL clients on %s:9042 \(unencrypted, non-shard-aware\)$`,
@@ -109,13 +110,16 @@ func NewWithImage(ctx context.Context, image string) (ScyllaDB, error) {
 		return nil, err
 	}
 
-	err = c.AwaitOutput(ctx, docker.NewRegexpMatcher(re))
+	c.SetWaitStrategy(docker.ForLog(docker.NewRegexpMatcher(re)))
+
+	err = c.Run(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	sd := &scylladb{
-		c: c,
+		Lifecycle: c,
+		c:         c,
 	}
 
 	cfg, err := sd.ClusterConfig("")
@@ -181,6 +185,11 @@ func (s *scylladb) DropKeyspace(name string) error {
 	return s.session.Query(q).Exec()
 }
 
+// Container returns the underlying container
+func (s *scylladb) Container() docker.Container {
+	return s.c
+}
+
 func (s *scylladb) Close(ctx context.Context) error {
 	if s.session != nil {
 		s.session.Close()
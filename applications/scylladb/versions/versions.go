@@ -7,12 +7,12 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/suite"
 
+	"github.com/teran/go-docker-testsuite"
 	"github.com/teran/go-docker-testsuite/applications/scylladb"
 )
 
 const (
 	ScyllaDBTestDefaultTimeout = 3 * time.Minute
-	CleanupDefaultTimeout      = 1 * time.Minute
 )
 
 type testSuite struct {
@@ -59,15 +59,7 @@ func New(ctx context.Context, image string) *testSuite {
 }
 
 func (s *testSuite) SetupSuite() {
-	var err error
-	s.sdb, err = scylladb.NewWithImage(s.T().Context(), s.image)
-	s.Require().NoError(err)
-}
-
-func (s *testSuite) TearDownSuite() {
-	ctx, cancel := context.WithTimeout(s.T().Context(), CleanupDefaultTimeout)
-	defer cancel()
-
-	err := s.sdb.Close(ctx)
-	s.Require().NoError(err)
+	s.sdb = docker.Start(s.T(), func() (scylladb.ScyllaDB, error) {
+		return scylladb.NewWithImage(s.T().Context(), s.image)
+	})
 }
@@ -17,6 +17,8 @@ import (
 var reTokenMatch = regexp.MustCompile(`Root Token: (.+)$`)
 
 type Vault interface {
+	docker.Lifecycle
+
 	ClusterAddr() (string, error)
 	APIAddr() (string, error)
 	GetRootToken(ctx context.Context) (string, error)
@@ -24,13 +26,16 @@ type Vault interface {
 	CreateEngine(ctx context.Context, mountPath, engineType string) error
 	RemoveEngine(ctx context.Context, mountPath string) error
 	Close(ctx context.Context) error
+	Container() docker.Container
 }
 
 type vaultImpl struct {
+	docker.Lifecycle
+
 	c docker.Container
 }
 
-func New(ctx context.Context, image string) (Vault, error) {
+func New(ctx context.Context, image string, opts ...docker.ContainerOption) (Vault, error) {
 	c, err := docker.NewContainer(
 		"vault",
 		image,
@@ -40,6 +45,7 @@ func New(ctx context.Context, image string) (Vault, error) {
 		docker.NewPortBindings().
 			PortDNAT(docker.ProtoTCP, 8200).
 			PortDNAT(docker.ProtoTCP, 8201),
+		opts...,
 	)
 	if err != nil {
 		return nil, err
@@ -54,19 +60,17 @@ func New(ctx context.Context, image string) (Vault, error) {
 		}
 	}()
 
-	err = c.Run(ctx)
-	if err != nil {
-		return nil, err
-	}
+	c.SetWaitStrategy(docker.ForLog(docker.NewRegexpMatcher(reTokenMatch)))
 
-	err = c.AwaitOutput(ctx, docker.NewRegexpMatcher(reTokenMatch))
+	err = c.Run(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	started = true
 	return &vaultImpl{
-		c: c,
+		Lifecycle: c,
+		c:         c,
 	}, nil
 }
 
@@ -118,6 +122,11 @@ func (v *vaultImpl) GetRootClient(ctx context.Context) (*vault.Client, error) {
 	return cli, nil
 }
 
+// Container returns the underlying container
+func (v *vaultImpl) Container() docker.Container {
+	return v.c
+}
+
 func (v *vaultImpl) Close(ctx context.Context) error {
 	return v.c.Close(ctx)
 }
@@ -0,0 +1,293 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types/build"
+	"github.com/docker/docker/client"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	labelBuildHash = "go-docker-testsuite.build-hash"
+
+	buildImageRepository = "go-docker-testsuite-build"
+	defaultDockerfile    = "Dockerfile"
+)
+
+// builtImages holds references of the images built by the testsuite:
+// they're local so IMAGE_PREFIX is not applied and they're never pulled
+var builtImages sync.Map
+
+// BuildOptions describes the image to build
+type BuildOptions struct {
+	// ContextDir is the local directory used as the build context
+	ContextDir string
+	// Context is the tar archive used as the build context when ContextDir
+	// is not set
+	Context io.Reader
+	// Dockerfile is the path to the Dockerfile within the build context,
+	// "Dockerfile" by default
+	Dockerfile string
+	// BuildArgs are passed as the build-time variables (ARG)
+	BuildArgs map[string]string
+	// Target is the build stage to build in multi-stage Dockerfile
+	Target string
+	// Tag is the additional reference the image is tagged with
+	Tag string
+}
+
+// BuildImage builds the image from the build context and returns the
+// reference to pass to NewContainer(). The image is tagged with the hash
+// of the build context and the options so the build is skipped when the
+// image with the same hash is already present.
+//
+// NOTE: .dockerignore is not applied to ContextDir: the whole directory
+// is sent to the Docker daemon.
+func BuildImage(ctx context.Context, opts *BuildOptions) (string, error) {
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return "", err
+	}
+
+	return BuildImageWithClient(ctx, cli, opts)
+}
+
+// BuildImageWithClient builds the image the same way as BuildImage and allows
+// to pass custom docker.Client instance
+func BuildImageWithClient(ctx context.Context, cli *client.Client, opts *BuildOptions) (string, error) {
+	buildCtx, err := opts.context()
+	if err != nil {
+		return "", err
+	}
+
+	hash := opts.hash(buildCtx)
+	ref := buildImageRepository + ":" + hash[:16]
+
+	ok, err := imageExists(ctx, cli, ref)
+	if err != nil {
+		return "", err
+	}
+
+	if ok {
+		log.WithFields(log.Fields{
+			"ref": ref,
+		}).Debug("image with the same build hash is present: skipping build")
+	} else {
+		if err := opts.build(ctx, cli, buildCtx, ref, hash); err != nil {
+			return "", err
+		}
+	}
+	builtImages.Store(ref, struct{}{})
+
+	if opts.Tag == "" {
+		return ref, nil
+	}
+
+	if err := cli.ImageTag(ctx, ref, opts.Tag); err != nil {
+		return "", errors.Wrapf(err, "error tagging image as `%s`", opts.Tag)
+	}
+	builtImages.Store(opts.Tag, struct{}{})
+
+	return opts.Tag, nil
+}
+
+func (o *BuildOptions) build(ctx context.Context, cli *client.Client, buildCtx []byte, ref, hash string) error {
+	log.WithFields(log.Fields{
+		"ref":        ref,
+		"dockerfile": o.dockerfile(),
+		"target":     o.Target,
+	}).Debug("building image")
+
+	args := map[string]*string{}
+	for k, v := range o.BuildArgs {
+		args[k] = &v
+	}
+
+	resp, err := cli.ImageBuild(ctx, bytes.NewReader(buildCtx), build.ImageBuildOptions{
+		Tags:        []string{ref},
+		Dockerfile:  o.dockerfile(),
+		BuildArgs:   args,
+		Target:      o.Target,
+		Remove:      true,
+		ForceRemove: true,
+		Labels: map[string]string{
+			labelBuildHash: hash,
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "error building image")
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	return errors.Wrap(readBuildOutput(resp.Body), "error building image")
+}
+
+// readBuildOutput reads the build progress stream till the end and returns
+// the build error reported by the Docker daemon if any
+func readBuildOutput(r io.Reader) error {
+	dec := json.NewDecoder(r)
+	for {
+		msg := jsonMessage{}
+		if err := dec.Decode(&msg); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		if err := msg.err(); err != nil {
+			return err
+		}
+
+		if line := strings.TrimRight(msg.Stream, "\n"); line != "" {
+			log.WithFields(log.Fields{
+				"line": line,
+			}).Trace("build output")
+		}
+	}
+}
+
+func (o *BuildOptions) dockerfile() string {
+	if o.Dockerfile == "" {
+		return defaultDockerfile
+	}
+	return o.Dockerfile
+}
+
+// context returns the build context archive: ContextDir is archived with
+// modification times and owners reset so the archive only depends on the
+// file names, modes and contents
+func (o *BuildOptions) context() ([]byte, error) {
+	if o.ContextDir == "" {
+		if o.Context == nil {
+			return nil, errors.New("either build context directory or build context archive must be set")
+		}
+
+		data, err := io.ReadAll(o.Context)
+		return data, errors.Wrap(err, "error reading build context")
+	}
+
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	err := filepath.WalkDir(o.ContextDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(o.ContextDir, p)
+		if err != nil {
+			return err
+		}
+
+		if rel == "." {
+			return nil
+		}
+
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		link := ""
+		if fi.Mode()&os.ModeSymlink != 0 {
+			link, err = os.Readlink(p)
+			if err != nil {
+				return err
+			}
+		}
+
+		hdr, err := tar.FileInfoHeader(fi, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if fi.IsDir() {
+			hdr.Name += "/"
+		}
+		hdr.ModTime = time.Unix(0, 0)
+		hdr.AccessTime, hdr.ChangeTime = time.Time{}, time.Time{}
+		hdr.Uid, hdr.Gid = 0, 0
+		hdr.Uname, hdr.Gname = "", ""
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+
+		fp, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = fp.Close() }()
+
+		_, err = io.Copy(tw, fp)
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "error archiving build context `%s`", o.ContextDir)
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, errors.Wrap(err, "error finalizing build context archive")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// hash returns the hash of the build context and the build options
+// affecting the resulting image
+func (o *BuildOptions) hash(buildCtx []byte) string {
+	args := []string{}
+	for k, v := range o.BuildArgs {
+		args = append(args, k+"="+v)
+	}
+	sort.Strings(args)
+
+	h := sha256.New()
+	_, _ = h.Write(buildCtx)
+	for _, part := range [][]string{
+		{o.dockerfile()},
+		args,
+		{o.Target},
+	} {
+		_, _ = h.Write([]byte{0xff})
+		_, _ = h.Write([]byte(strings.Join(part, "\x00")))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func imageExists(ctx context.Context, cli *client.Client, ref string) (bool, error) {
+	_, err := cli.ImageInspect(ctx, ref)
+	if err == nil {
+		return true, nil
+	}
+
+	if cerrdefs.IsNotFound(err) {
+		return false, nil
+	}
+	return false, errors.Wrapf(err, "error inspecting image `%s`", ref)
+}
+
+func isBuiltImage(ref string) bool {
+	_, ok := builtImages.Load(ref)
+	return ok
+}
@@ -0,0 +1,101 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildContext(t *testing.T) {
+	r := require.New(t)
+
+	dir := t.TempDir()
+	r.NoError(os.MkdirAll(filepath.Join(dir, "cmd"), 0o755))
+	r.NoError(os.WriteFile(filepath.Join(dir, "Dockerfile"), []byte("FROM scratch\nCOPY cmd /cmd\n"), 0o644))
+	r.NoError(os.WriteFile(filepath.Join(dir, "cmd", "app"), []byte("binary"), 0o755))
+
+	opts := &BuildOptions{ContextDir: dir}
+
+	ctx1, err := opts.context()
+	r.NoError(err)
+
+	// Modification time doesn't affect the archive
+	r.NoError(os.Chtimes(filepath.Join(dir, "Dockerfile"), time.Now(), time.Now().Add(-time.Hour)))
+
+	ctx2, err := opts.context()
+	r.NoError(err)
+	r.Equal(ctx1, ctx2)
+
+	names := []string{}
+	tr := tar.NewReader(bytes.NewReader(ctx1))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		r.NoError(err)
+		names = append(names, hdr.Name)
+	}
+	r.Equal([]string{"Dockerfile", "cmd/", "cmd/app"}, names)
+
+	r.NoError(os.WriteFile(filepath.Join(dir, "cmd", "app"), []byte("binary v2"), 0o755))
+
+	ctx3, err := opts.context()
+	r.NoError(err)
+	r.NotEqual(ctx1, ctx3)
+
+	_, err = (&BuildOptions{}).context()
+	r.Error(err)
+
+	data, err := (&BuildOptions{Context: strings.NewReader("archive")}).context()
+	r.NoError(err)
+	r.Equal([]byte("archive"), data)
+}
+
+func TestBuildHash(t *testing.T) {
+	r := require.New(t)
+
+	buildCtx := []byte("archive")
+
+	h1 := (&BuildOptions{BuildArgs: map[string]string{"A": "1", "B": "2"}}).hash(buildCtx)
+	h2 := (&BuildOptions{Dockerfile: "Dockerfile", BuildArgs: map[string]string{"B": "2", "A": "1"}, Tag: "app:test"}).hash(buildCtx)
+	r.Equal(h1, h2)
+
+	r.NotEqual(h1, (&BuildOptions{BuildArgs: map[string]string{"A": "1", "B": "3"}}).hash(buildCtx))
+	r.NotEqual(h1, (&BuildOptions{BuildArgs: map[string]string{"A": "1", "B": "2"}, Target: "test"}).hash(buildCtx))
+	r.NotEqual(h1, (&BuildOptions{BuildArgs: map[string]string{"A": "1", "B": "2"}, Dockerfile: "Dockerfile.test"}).hash(buildCtx))
+	r.NotEqual(h1, (&BuildOptions{BuildArgs: map[string]string{"A": "1", "B": "2"}}).hash([]byte("other archive")))
+}
+
+func TestReadBuildOutput(t *testing.T) {
+	r := require.New(t)
+
+	err := readBuildOutput(strings.NewReader(`{"stream":"Step 1/2 : FROM scratch\n"}
+{"stream":" ---> Running\n"}
+{"aux":{"ID":"sha256:abc"}}`))
+	r.NoError(err)
+
+	err = readBuildOutput(strings.NewReader(`{"stream":"Step 1/2 : FROM scratch\n"}
+{"errorDetail":{"message":"COPY failed: file not found"},"error":"COPY failed: file not found"}`))
+	r.EqualError(err, "COPY failed: file not found")
+}
+
+func TestBuiltImageIsNotPrefixed(t *testing.T) {
+	r := require.New(t)
+
+	t.Setenv("IMAGE_PREFIX", "test-prefix")
+
+	builtImages.Store("app:built", struct{}{})
+	t.Cleanup(func() { builtImages.Delete("app:built") })
+
+	c, err := NewContainerWithClient(nil, "test", "app:built", nil, NewEnvironment(), NewPortBindings())
+	r.NoError(err)
+	r.Equal("app:built", c.(*container).image)
+}
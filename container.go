@@ -6,65 +6,51 @@ import (
 	"io"
 	"os"
 	"strconv"
-	"strings"
 	"time"
 
 	dockerContainer "github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
-
-	"github.com/teran/go-docker-testsuite/internal/ptr"
 )
 
 const (
 	defaultStopTimeout = 1 * time.Minute
 )
 
-var errImageIsNotPulled = errors.New("image is not pulled")
-
 type (
 	ContainerID = string
 	NetworkID   = string
 )
 
-// ContainerOption modifies the docker HostConfig before container creation.
-type ContainerOption func(*dockerContainer.HostConfig)
-
-// WithPrivileged grants the container elevated privileges.
-func WithPrivileged() ContainerOption {
-	return func(hc *dockerContainer.HostConfig) {
-		hc.Privileged = true
-	}
-}
-
-// WithTmpfs mounts tmpfs filesystems at the given paths.
-func WithTmpfs(m map[string]string) ContainerOption {
-	return func(hc *dockerContainer.HostConfig) {
-		hc.Tmpfs = m
-	}
-}
-
-// WithBinds adds volume bind mounts (host:container[:mode]).
-func WithBinds(binds ...string) ContainerOption {
-	return func(hc *dockerContainer.HostConfig) {
-		hc.Binds = binds
-	}
-}
-
 // Container exposes interface to control the container runtime
 type Container interface {
+	Lifecycle
+
+	AddLogConsumer(lcs ...LogConsumer)
+	AwaitHealthy(ctx context.Context) error
 	AwaitOutput(ctx context.Context, m Matcher) error
 	GetOutput(ctx context.Context, m ...Matcher) ([]string, error)
 	Close(ctx context.Context) error
+	CopyFrom(ctx context.Context, srcPath string) (io.ReadCloser, error)
+	CopyPathTo(ctx context.Context, srcPath, dstPath string) error
+	CopyTo(ctx context.Context, src io.Reader, dstPath string, mode os.FileMode) error
+	Exec(ctx context.Context, cmd []string, opts *ExecOptions) (*ExecResult, error)
 	ID() ContainerID
+	Inspect(ctx context.Context) (*InspectResult, error)
 	Name() string
 	NetworkAttach(networkID string) error
 	Ping(ctx context.Context) error
+	Platform() string
+	ResetNetworkConditions(ctx context.Context) error
 	Run(ctx context.Context) error
+	SetHealthcheck(hc *Healthcheck)
+	SetNetworkConditions(ctx context.Context, nc NetworkConditions) error
+	SetReuse(enabled bool)
+	SetWaitStrategy(ws WaitStrategy)
 	URL(proto Protocol, port uint16) (*HostPort, error)
+	Wait(ctx context.Context) (*WaitResult, error)
 }
 
 type container struct {
@@ -72,6 +58,7 @@ type container struct {
 
 	name          string
 	image         string
+	originalImage string
 	env           Environment
 	cmd           []string
 	containerID   ContainerID
@@ -79,6 +66,16 @@ type container struct {
 	ports         *PortBindings
 	indirectPorts map[string]string
 	containerOpts []ContainerOption
+	pendingCopies [][]byte
+	waitStrategy  WaitStrategy
+	healthcheck   *Healthcheck
+	reuse         *bool
+	logConsumers  []LogConsumer
+	logsCancel    context.CancelFunc
+	logsDone      chan struct{}
+	startedAt     string
+	config        *ContainerConfig
+	platform      string
 }
 
 // New creates new container instance from remote docker image
@@ -99,20 +96,16 @@ func NewContainerWithClient(cli *client.Client, name, image string, cmd []string
 		"image": image,
 	}).Debugf("initializing container")
 
-	imageRef := image
-	prefix := os.Getenv("IMAGE_PREFIX")
-	if prefix != "" {
-		imageRef = strings.TrimRight(prefix, "/") + "/" + strings.TrimLeft(imageRef, "/")
-		log.WithFields(log.Fields{
-			"original": image,
-			"prefixed": imageRef,
-		}).Trace("Setting prefix for image (for proxy purposes since IMAGE_PREFIX is present)")
+	imageRef, err := imageReference(image)
+	if err != nil {
+		return nil, err
 	}
 
 	return &container{
 		cli:           cli,
 		name:          name,
 		image:         imageRef,
+		originalImage: image,
 		cmd:           cmd,
 		env:           env,
 		ports:         ports,
@@ -121,18 +114,30 @@ func NewContainerWithClient(cli *client.Client, name, image string, cmd []string
 	}, nil
 }
 
-// AwaitOutput blocks the execution for any of (whatever comes first): string matched Matcher or timeout
+// AwaitOutput blocks the execution for any of (whatever comes first): string matched Matcher or timeout.
+// Only the output since the last Start() is considered. ErrContainerExited is returned if the output
+// ends (the container exited) before the matched line.
 func (c *container) AwaitOutput(ctx context.Context, m Matcher) error {
 	rd, err := c.cli.ContainerLogs(ctx, c.containerID, dockerContainer.LogsOptions{
 		ShowStderr: true,
 		ShowStdout: true,
 		Follow:     true,
+		Since:      c.startedAt,
 	})
 	if err != nil {
 		return err
 	}
 	defer func() { _ = rd.Close() }()
 
+	if err := awaitOutput(ctx, rd, m); err != nil {
+		return errors.Wrapf(err, "error awaiting output of `%s`", c.name)
+	}
+	return nil
+}
+
+// awaitOutput scans the followed output until the line matched by Matcher,
+// the end of the output means the container is exited
+func awaitOutput(ctx context.Context, rd io.Reader, m Matcher) error {
 	s := bufio.NewScanner(rd)
 	for s.Scan() {
 		select {
@@ -151,7 +156,10 @@ func (c *container) AwaitOutput(ctx context.Context, m Matcher) error {
 		}
 	}
 
-	return s.Err()
+	if err := s.Err(); err != nil {
+		return err
+	}
+	return errors.Wrap(ErrContainerExited, "output ended without the matching line")
 }
 
 func (c *container) GetOutput(ctx context.Context, ms ...Matcher) ([]string, error) {
@@ -208,60 +216,178 @@ func (c *container) Ping(ctx context.Context) error {
 
 // Run starts the container
 func (c *container) Run(ctx context.Context) error {
-	err := c.pullImage(ctx)
+	reapOrphansOnce(ctx, c.cli)
+
+	env := c.env.Eval(newContainerInfoFromContainer(c))
+	cc := c.containerConfig(env)
+
+	platform, err := parsePlatform(cc.Platform)
 	if err != nil {
 		return err
 	}
 
-	containerConfig := &dockerContainer.Config{
-		Image:        c.image,
-		Env:          c.env.Eval(newContainerInfoFromContainer(c)),
-		Cmd:          c.cmd,
-		ExposedPorts: c.ports.portSet(),
-		Labels: map[string]string{
-			"go-docker-testsuite.name": c.name,
-		},
-	}
+	if c.reuseEnabled() {
+		hash := c.configHash(cc)
+		cc.Config.Labels[labelReuseHash] = hash
 
-	networkConfig := &network.NetworkingConfig{}
+		ok, err := c.lookupReusable(ctx, hash)
+		if err != nil {
+			return err
+		}
 
-	log.WithFields(log.Fields{
-		"ports": c.ports,
-	}).Trace("creating new host config ...")
+		if ok {
+			c.config = cc
+			// The archives are part of the hash so the reused container
+			// already has them
+			c.pendingCopies = nil
+
+			if err := c.connectNetwork(ctx); err != nil {
+				return err
+			}
+
+			if err := c.resolvePlatform(ctx); err != nil {
+				return err
+			}
+
+			since := strconv.FormatInt(time.Now().Unix(), 10)
+			if err := c.followLogs(since); err != nil {
+				return err
+			}
+			return c.waitUntilReady(ctx)
+		}
+	}
 
-	hostConfig, err := NewHostConfig(c.ports, c.containerOpts...)
+	err = c.pullImage(ctx, cc, platform)
 	if err != nil {
-		return errors.Wrap(err, "error gathering host configuration")
+		return err
 	}
 
+	log.WithFields(log.Fields{
+		"ports": c.ports,
+	}).Trace("creating container ...")
+
 	container, err := c.cli.ContainerCreate(
 		ctx,
-		containerConfig,
-		hostConfig,
-		networkConfig,
-		nil,
+		cc.Config,
+		cc.HostConfig,
+		cc.NetworkingConfig,
+		platform,
 		"",
 	)
 	if err != nil {
 		return errors.Wrap(err, "error creating container")
 	}
 
+	c.config = cc
 	c.containerID = container.ID
 
-	if c.networkID != "" {
-		err := c.cli.NetworkConnect(ctx, c.networkID, c.containerID, &network.EndpointSettings{
-			Aliases: []string{c.name},
-		})
+	if err := c.connectNetwork(ctx); err != nil {
+		return err
+	}
+
+	if err := c.resolvePlatform(ctx); err != nil {
+		return err
+	}
+
+	for _, archive := range c.pendingCopies {
+		err := c.copyArchive(ctx, archive)
 		if err != nil {
 			return err
 		}
 	}
+	c.pendingCopies = nil
 
 	err = c.cli.ContainerStart(ctx, c.containerID, dockerContainer.StartOptions{})
-	return errors.Wrap(err, "error starting container")
+	if err != nil {
+		return errors.Wrap(err, "error starting container")
+	}
+
+	if err := c.followLogs(""); err != nil {
+		return err
+	}
+
+	return c.waitUntilReady(ctx)
+}
+
+func (c *container) connectNetwork(ctx context.Context) error {
+	if c.networkID == "" {
+		return nil
+	}
+
+	return c.cli.NetworkConnect(ctx, c.networkID, c.containerID, &network.EndpointSettings{
+		Aliases: []string{c.name},
+	})
+}
+
+// containerConfig builds the configuration of the container with options
+// applied. Labels used by the testsuite are set last so options can't
+// override them.
+func (c *container) containerConfig(env []string) *ContainerConfig {
+	cc := newContainerConfig(c.ports)
+	cc.Config.Image = c.image
+	cc.Config.Env = env
+	cc.Config.Cmd = c.cmd
+	cc.Config.Healthcheck = c.healthcheck.healthConfig()
+
+	for _, opt := range c.containerOpts {
+		opt(cc)
+	}
+
+	if cc.Config.Labels == nil {
+		cc.Config.Labels = map[string]string{}
+	}
+	for k, v := range sessionLabels() {
+		cc.Config.Labels[k] = v
+	}
+	cc.Config.Labels[labelName] = c.name
+
+	return cc
+}
+
+// stopOptions returns the options to stop the container with: the stop
+// timeout configured with WithStopTimeout() is used by Docker if set,
+// the time left until the context deadline otherwise
+func (c *container) stopOptions(ctx context.Context) dockerContainer.StopOptions {
+	if c.config != nil && c.config.Config.StopTimeout != nil {
+		return dockerContainer.StopOptions{}
+	}
+
+	return dockerContainer.StopOptions{
+		Timeout: stopTimeout(ctx),
+	}
 }
 
-// Close cleans up the env (stops & removes the container)
+// SetWaitStrategy sets the strategy Run() uses to await the container readiness
+func (c *container) SetWaitStrategy(ws WaitStrategy) {
+	c.waitStrategy = ws
+}
+
+func (c *container) waitUntilReady(ctx context.Context) error {
+	if c.waitStrategy == nil {
+		return nil
+	}
+
+	log.WithFields(log.Fields{
+		"name": c.name,
+	}).Trace("waiting for container readiness")
+
+	err := c.waitStrategy.WaitUntilReady(ctx, c)
+	if err == nil {
+		return nil
+	}
+	return c.startupError(errors.Wrapf(err, "error waiting for container `%s` readiness", c.name))
+}
+
+func (c *container) inspect(ctx context.Context) (dockerContainer.InspectResponse, error) {
+	if c.containerID == "" {
+		return dockerContainer.InspectResponse{}, ErrContainerIsNotRunning
+	}
+
+	return c.cli.ContainerInspect(ctx, c.containerID)
+}
+
+// Close cleans up the env (stops & removes the container). In reuse mode
+// the running container is left running.
 func (c *container) Close(ctx context.Context) error {
 	if c.containerID == "" {
 		return nil
@@ -275,20 +401,28 @@ func (c *container) Close(ctx context.Context) error {
 		defer cancel()
 	}
 
-	timeout := defaultStopTimeout
-	if dl, ok := ctx.Deadline(); ok {
-		if remaining := time.Until(dl); remaining > 0 {
-			timeout = remaining
+	if c.reuseEnabled() && c.isRunning(ctx) {
+		log.WithFields(log.Fields{
+			"name": c.name,
+			"id":   c.containerID,
+		}).Debug("reuse mode is enabled: leaving the container running")
+
+		c.stopLogs(false)
+
+		if c.networkID == "" {
+			return nil
 		}
+		return c.cli.NetworkDisconnect(ctx, c.networkID, c.containerID, true)
 	}
 
-	err := c.cli.ContainerStop(ctx, c.containerID, dockerContainer.StopOptions{
-		Timeout: ptr.Ptr[int](int(timeout / time.Second)),
-	})
+	err := c.cli.ContainerStop(ctx, c.containerID, c.stopOptions(ctx))
 	if err != nil {
+		c.stopLogs(false)
 		return err
 	}
 
+	c.stopLogs(true)
+
 	err = c.cli.ContainerRemove(ctx, c.containerID, dockerContainer.RemoveOptions{
 		RemoveVolumes: true,
 		Force:         true,
@@ -300,46 +434,14 @@ func (c *container) Close(ctx context.Context) error {
 	return nil
 }
 
-func (c *container) pullImage(ctx context.Context) error {
-	isLatest := strings.HasSuffix(c.image, ":latest")
-
-	err := c.isImagePulled(ctx)
-	if err != nil && err != errImageIsNotPulled {
-		return err
-	}
-
-	if isLatest || err == errImageIsNotPulled {
-		rc, err := c.cli.ImagePull(ctx, c.image, image.PullOptions{})
-		if err != nil {
-			return errors.Wrap(err, "error pulling image")
-		}
-		defer func() { _ = rc.Close() }()
-
-		// Drain the pull response to wait for the pull to complete.
-		_, err = io.Copy(io.Discard, rc)
-		if err != nil {
-			return errors.Wrap(err, "error waiting for image pull to complete")
-		}
-	}
-
-	return nil
-}
-
-func (c *container) isImagePulled(ctx context.Context) error {
-	images, err := c.cli.ImageList(ctx, image.ListOptions{})
+// isRunning reports whether the container is running, the stopped one is
+// never picked up for reuse so it's removed on Close() even in reuse mode
+func (c *container) isRunning(ctx context.Context) bool {
+	info, err := c.inspect(ctx)
 	if err != nil {
-		return err
+		return true
 	}
-
-	for _, image := range images {
-		for _, tag := range image.RepoTags {
-			if tag == c.image {
-				return nil
-			}
-		}
-	}
-
-	return errImageIsNotPulled
+	return info.State != nil && info.State.Running
 }
 
 // URL returns host & port pair to allow external connections
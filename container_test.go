@@ -1,10 +1,19 @@
 package docker
 
 import (
+	"archive/tar"
+	"bufio"
 	"context"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types/image"
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
@@ -12,6 +21,7 @@ import (
 
 	"github.com/teran/echo-grpc-server/presenter/proto"
 	"github.com/teran/go-docker-testsuite/images"
+	"github.com/teran/go-docker-testsuite/internal/random"
 )
 
 func init() {
@@ -86,3 +96,793 @@ func TestContainerRun(t *testing.T) {
 	r.NoError(err)
 	r.Equal("some another message", resp.GetMessage())
 }
+
+func TestContainerExec(t *testing.T) {
+	r := require.New(t)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Minute)
+	defer cancel()
+
+	c, err := NewContainer(
+		"test-exec",
+		images.Memcache,
+		nil,
+		NewEnvironment(),
+		NewPortBindings(),
+	)
+	r.NoError(err)
+
+	_, err = c.Exec(ctx, []string{"true"}, nil)
+	r.ErrorIs(err, ErrContainerIsNotRunning)
+
+	err = c.Run(ctx)
+	r.NoError(err)
+
+	defer func() { _ = c.Close(ctx) }()
+
+	res, err := c.Exec(ctx, []string{"sh", "-c", "echo $TEST_VAR; pwd; echo oops >&2; cat; exit 3"}, &ExecOptions{
+		Env:        NewEnvironment().StringVar("TEST_VAR", "test value"),
+		WorkingDir: "/tmp",
+		Stdin:      strings.NewReader("from stdin"),
+	})
+	r.NoError(err)
+	r.Equal(3, res.ExitCode)
+	r.Equal("test value\n/tmp\nfrom stdin", string(res.Stdout))
+	r.Equal("oops\n", string(res.Stderr))
+
+	res, err = c.Exec(ctx, []string{"id", "-u"}, &ExecOptions{
+		User: "root",
+	})
+	r.NoError(err)
+	r.Equal(0, res.ExitCode)
+	r.Equal("0\n", string(res.Stdout))
+}
+
+func TestContainerCopy(t *testing.T) {
+	r := require.New(t)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Minute)
+	defer cancel()
+
+	c, err := NewContainer(
+		"test-copy",
+		images.Memcache,
+		nil,
+		NewEnvironment(),
+		NewPortBindings(),
+	)
+	r.NoError(err)
+
+	err = c.CopyTo(ctx, strings.NewReader("before run"), "/tmp/testdata/before.txt", 0o644)
+	r.NoError(err)
+
+	_, err = c.CopyFrom(ctx, "/tmp/testdata/before.txt")
+	r.ErrorIs(err, ErrContainerIsNotRunning)
+
+	err = c.Run(ctx)
+	r.NoError(err)
+
+	defer func() { _ = c.Close(ctx) }()
+
+	dir := t.TempDir()
+	r.NoError(os.MkdirAll(filepath.Join(dir, "nested"), 0o755))
+	r.NoError(os.WriteFile(filepath.Join(dir, "nested", "file.txt"), []byte("from host"), 0o600))
+
+	err = c.CopyPathTo(ctx, dir, "/tmp/fromhost")
+	r.NoError(err)
+
+	err = c.CopyTo(ctx, strings.NewReader("after run"), "/tmp/after.txt", 0o600)
+	r.NoError(err)
+
+	for path, expected := range map[string]string{
+		"/tmp/testdata/before.txt":      "before run",
+		"/tmp/fromhost/nested/file.txt": "from host",
+		"/tmp/after.txt":                "after run",
+	} {
+		rc, err := c.CopyFrom(ctx, path)
+		r.NoError(err)
+
+		data, err := io.ReadAll(rc)
+		r.NoError(err)
+		r.NoError(rc.Close())
+		r.Equal(expected, string(data))
+	}
+
+	rc, err := c.CopyFrom(ctx, "/tmp/fromhost")
+	r.NoError(err)
+	defer func() { _ = rc.Close() }()
+
+	names := []string{}
+	tr := tar.NewReader(rc)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		r.NoError(err)
+		names = append(names, hdr.Name)
+	}
+	r.ElementsMatch([]string{"fromhost/", "fromhost/nested/", "fromhost/nested/file.txt"}, names)
+}
+
+func TestContainerWaitStrategy(t *testing.T) {
+	r := require.New(t)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Minute)
+	defer cancel()
+
+	c, err := NewContainer(
+		"test-wait",
+		images.Memcache,
+		nil,
+		NewEnvironment(),
+		NewPortBindings().
+			PortDNAT(ProtoTCP, 11211),
+	)
+	r.NoError(err)
+
+	c.SetWaitStrategy(WithTimeout(ForAll(
+		ForListeningPort(ProtoTCP, 11211),
+		ForExec("sh", "-c", "test -d /proc/1"),
+		ForAny(
+			ForExec("false"),
+			ForExec("false").WithExitCode(1),
+		),
+	), 1*time.Minute))
+
+	err = c.Run(ctx)
+	r.NoError(err)
+
+	defer func() { _ = c.Close(ctx) }()
+
+	err = ForHealthy().WaitUntilReady(ctx, c)
+	r.ErrorIs(err, ErrNoHealthcheck)
+
+	crashed, err := NewContainer("test-wait-crashed", images.Alpine, []string{"sh", "-c", "echo starting; exit 1"}, NewEnvironment(), NewPortBindings())
+	r.NoError(err)
+
+	crashed.SetWaitStrategy(WithTimeout(ForLog(NewSubstringMatcher("ready")), 1*time.Minute))
+
+	defer func() { _ = crashed.Close(ctx) }()
+
+	err = crashed.Run(ctx)
+	r.ErrorIs(err, ErrContainerExited)
+}
+
+func TestContainerHealthcheck(t *testing.T) {
+	r := require.New(t)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Minute)
+	defer cancel()
+
+	c, err := NewContainer(
+		"test-healthcheck",
+		images.Memcache,
+		nil,
+		NewEnvironment(),
+		NewPortBindings(),
+	)
+	r.NoError(err)
+
+	c.SetHealthcheck(&Healthcheck{
+		Test:     []string{"CMD-SHELL", "test -d /proc/1"},
+		Interval: 1 * time.Second,
+		Retries:  3,
+	})
+	c.SetWaitStrategy(ForHealthy())
+
+	err = c.Run(ctx)
+	r.NoError(err)
+
+	defer func() { _ = c.Close(ctx) }()
+
+	err = c.AwaitHealthy(ctx)
+	r.NoError(err)
+
+	unhealthy, err := NewContainer(
+		"test-unhealthy",
+		images.Memcache,
+		nil,
+		NewEnvironment(),
+		NewPortBindings(),
+	)
+	r.NoError(err)
+
+	unhealthy.SetHealthcheck(&Healthcheck{
+		Test:     []string{"false"},
+		Interval: 1 * time.Second,
+		Retries:  1,
+	})
+
+	err = unhealthy.Run(ctx)
+	r.NoError(err)
+
+	defer func() { _ = unhealthy.Close(ctx) }()
+
+	err = unhealthy.AwaitHealthy(ctx)
+	r.ErrorIs(err, ErrContainerIsUnhealthy)
+}
+
+func TestContainerLogConsumers(t *testing.T) {
+	r := require.New(t)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Minute)
+	defer cancel()
+
+	c, err := NewContainer(
+		"test-log-consumers",
+		images.Memcache,
+		[]string{"sh", "-c", "echo to stdout; echo to stderr >&2"},
+		NewEnvironment(),
+		NewPortBindings(),
+	)
+	r.NoError(err)
+
+	buf := NewRingBufferLogConsumer(10)
+	c.AddLogConsumer(buf, NewTestingLogConsumer(t, "test-log-consumers"))
+
+	err = c.Run(ctx)
+	r.NoError(err)
+
+	err = c.Close(ctx)
+	r.NoError(err)
+
+	entries := buf.Entries()
+	r.Len(entries, 2)
+
+	streams := map[string]LogStream{}
+	for _, e := range entries {
+		r.False(e.Timestamp.IsZero())
+		streams[e.Line] = e.Stream
+	}
+	r.Equal(map[string]LogStream{
+		"to stdout": LogStreamStdout,
+		"to stderr": LogStreamStderr,
+	}, streams)
+}
+
+func TestRunContainer(t *testing.T) {
+	r := require.New(t)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Minute)
+	defer cancel()
+
+	c, err := NewContainer(
+		"test-run-container",
+		images.Memcache,
+		nil,
+		NewEnvironment(),
+		NewPortBindings(),
+	)
+	r.NoError(err)
+
+	c = RunContainer(t, ctx, c)
+	r.NotEmpty(c.ID())
+
+	res, err := c.Exec(ctx, []string{"true"}, nil)
+	r.NoError(err)
+	r.Equal(0, res.ExitCode)
+
+	entries, err := c.(*container).tailLogs(ctx, 10)
+	r.NoError(err)
+	r.LessOrEqual(len(entries), 10)
+}
+
+func TestContainerLifecycle(t *testing.T) {
+	r := require.New(t)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Minute)
+	defer cancel()
+
+	c, err := NewContainer(
+		"test-lifecycle",
+		images.Memcache,
+		nil,
+		NewEnvironment(),
+		NewPortBindings().PortDNAT(ProtoTCP, 11211),
+	)
+	r.NoError(err)
+
+	c.SetWaitStrategy(ForListeningPort(ProtoTCP, 11211))
+
+	err = c.Run(ctx)
+	r.NoError(err)
+
+	defer func() { _ = c.Close(ctx) }()
+
+	url, err := c.URL(ProtoTCP, 11211)
+	r.NoError(err)
+
+	err = c.Restart(ctx)
+	r.NoError(err)
+
+	restartedURL, err := c.URL(ProtoTCP, 11211)
+	r.NoError(err)
+	r.Equal(url, restartedURL)
+
+	err = c.Pause(ctx)
+	r.NoError(err)
+
+	_, err = c.Exec(ctx, []string{"true"}, nil)
+	r.Error(err)
+
+	err = c.Unpause(ctx)
+	r.NoError(err)
+
+	err = c.Kill(ctx, "SIGKILL")
+	r.NoError(err)
+
+	err = c.Start(ctx)
+	r.NoError(err)
+
+	res, err := c.Exec(ctx, []string{"true"}, nil)
+	r.NoError(err)
+	r.Equal(0, res.ExitCode)
+}
+
+func TestContainerNetworkConditions(t *testing.T) {
+	r := require.New(t)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Minute)
+	defer cancel()
+
+	c, err := NewContainer(
+		"test-network-conditions",
+		images.Memcache,
+		nil,
+		NewEnvironment(),
+		NewPortBindings().PortDNAT(ProtoTCP, 11211),
+	)
+	r.NoError(err)
+
+	c.SetWaitStrategy(ForListeningPort(ProtoTCP, 11211))
+
+	err = c.Run(ctx)
+	r.NoError(err)
+
+	defer func() { _ = c.Close(ctx) }()
+
+	url, err := c.URL(ProtoTCP, 11211)
+	r.NoError(err)
+
+	roundTrip := func() time.Duration {
+		start := time.Now()
+
+		conn, err := net.DialTimeout("tcp", url.String(), 10*time.Second)
+		r.NoError(err)
+		defer func() { _ = conn.Close() }()
+
+		_, err = conn.Write([]byte("version\r\n"))
+		r.NoError(err)
+
+		_, err = bufio.NewReader(conn).ReadString('\n')
+		r.NoError(err)
+
+		return time.Since(start)
+	}
+
+	err = c.SetNetworkConditions(ctx, NetworkConditions{
+		Latency: 500 * time.Millisecond,
+	})
+	r.NoError(err)
+	r.GreaterOrEqual(roundTrip(), 500*time.Millisecond)
+
+	err = c.ResetNetworkConditions(ctx)
+	r.NoError(err)
+	r.Less(roundTrip(), 500*time.Millisecond)
+
+	err = c.SetNetworkConditions(ctx, NetworkConditions{
+		Loss: 200,
+	})
+	r.Error(err)
+}
+
+func TestContainerOptions(t *testing.T) {
+	r := require.New(t)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Minute)
+	defer cancel()
+
+	c, err := NewContainer(
+		"test-container-options",
+		images.Memcache,
+		[]string{"-c", "pwd; id -u; hostname; exec sleep 3600"},
+		NewEnvironment(),
+		NewPortBindings(),
+		WithEntrypoint("/bin/sh"),
+		WithUser("nobody"),
+		WithWorkingDir("/tmp"),
+		WithHostname("custom-host"),
+		WithStopSignal("SIGKILL"),
+		WithStopTimeout(1*time.Second),
+	)
+	r.NoError(err)
+
+	c.SetWaitStrategy(ForLog(NewSubstringMatcher("custom-host")))
+
+	err = c.Run(ctx)
+	r.NoError(err)
+
+	out, err := c.GetOutput(ctx, NewSubstringMatcher("/tmp"), NewSubstringMatcher("65534"))
+	r.NoError(err)
+	r.Len(out, 2)
+
+	err = c.Close(ctx)
+	r.NoError(err)
+}
+
+func TestContainerSecurityOptions(t *testing.T) {
+	r := require.New(t)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Minute)
+	defer cancel()
+
+	c, err := NewContainer(
+		"test-security-options",
+		images.Memcache,
+		nil,
+		NewEnvironment(),
+		NewPortBindings(),
+		WithReadOnlyRootfs(),
+		WithTmpfsSize("/scratch", 1024*1024),
+		WithMemoryLimit(64*1024*1024),
+		WithPidsLimit(32),
+		WithCapDrop("ALL"),
+		WithSecurityOpt("no-new-privileges"),
+	)
+	r.NoError(err)
+
+	c = RunContainer(t, ctx, c)
+
+	res, err := c.Exec(ctx, []string{"touch", "/file"}, nil)
+	r.NoError(err)
+	r.NotEqual(0, res.ExitCode)
+
+	res, err = c.Exec(ctx, []string{"touch", "/scratch/file"}, nil)
+	r.NoError(err)
+	r.Equal(0, res.ExitCode)
+
+	res, err = c.Exec(ctx, []string{"dd", "if=/dev/zero", "of=/scratch/big", "bs=1M", "count=2"}, nil)
+	r.NoError(err)
+	r.NotEqual(0, res.ExitCode)
+}
+
+func TestContainerReuse(t *testing.T) {
+	r := require.New(t)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Minute)
+	defer cancel()
+
+	newContainer := func() Container {
+		c, err := NewContainer(
+			"test-reuse",
+			images.Memcache,
+			[]string{"memcached", "-vv"},
+			NewEnvironment(),
+			NewPortBindings().
+				PortDNAT(ProtoTCP, 11211),
+		)
+		r.NoError(err)
+
+		c.SetReuse(true)
+		c.SetWaitStrategy(ForListeningPort(ProtoTCP, 11211))
+		return c
+	}
+
+	c1 := newContainer()
+	err := c1.Run(ctx)
+	r.NoError(err)
+
+	err = c1.Close(ctx)
+	r.NoError(err)
+
+	c2 := newContainer()
+	err = c2.Run(ctx)
+	r.NoError(err)
+	r.Equal(c1.ID(), c2.ID())
+
+	hp1, err := c1.URL(ProtoTCP, 11211)
+	r.NoError(err)
+
+	hp2, err := c2.URL(ProtoTCP, 11211)
+	r.NoError(err)
+	r.Equal(hp1.Port, hp2.Port)
+
+	c2.SetReuse(false)
+	err = c2.Close(ctx)
+	r.NoError(err)
+}
+
+func TestContainerBuildImage(t *testing.T) {
+	r := require.New(t)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Minute)
+	defer cancel()
+
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "Dockerfile"), []byte(`FROM `+images.Memcache+` AS base
+ARG GREETING
+RUN echo "$GREETING" > /tmp/greeting
+
+FROM base AS test
+ENTRYPOINT ["/bin/sh", "-c", "cat /tmp/greeting; exec sleep 3600"]
+`), 0o644)
+	r.NoError(err)
+
+	opts := &BuildOptions{
+		ContextDir: dir,
+		BuildArgs:  map[string]string{"GREETING": "built image"},
+		Target:     "test",
+		Tag:        "go-docker-testsuite-test:build",
+	}
+
+	ref, err := BuildImage(ctx, opts)
+	r.NoError(err)
+	r.Equal("go-docker-testsuite-test:build", ref)
+
+	// The second build is served from cache
+	ref2, err := BuildImage(ctx, opts)
+	r.NoError(err)
+	r.Equal(ref, ref2)
+
+	c, err := NewContainer("test-build-image", ref, nil, NewEnvironment(), NewPortBindings())
+	r.NoError(err)
+
+	c.SetWaitStrategy(ForLog(NewSubstringMatcher("built image")))
+
+	RunContainer(t, ctx, c)
+}
+
+func TestContainerBuildGoImage(t *testing.T) {
+	r := require.New(t)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Minute)
+	defer cancel()
+
+	ref, err := BuildGoImage(ctx, &GoBuildOptions{
+		Package: "./testdata/hello",
+	})
+	r.NoError(err)
+
+	c, err := NewContainer("test-build-go-image", ref, []string{"go", "image"}, NewEnvironment(), NewPortBindings())
+	r.NoError(err)
+
+	c.SetWaitStrategy(ForLog(NewSubstringMatcher("hello from go image")))
+
+	RunContainer(t, ctx, c)
+}
+
+func TestContainerPullPolicyNever(t *testing.T) {
+	r := require.New(t)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Minute)
+	defer cancel()
+
+	c, err := NewContainer(
+		"test-pull-policy-never",
+		"index.docker.io/library/memcached:0.0.0-missing",
+		nil,
+		NewEnvironment(),
+		NewPortBindings(),
+		WithPullPolicy(PullNever),
+	)
+	r.NoError(err)
+
+	err = c.Run(ctx)
+	r.ErrorIs(err, ErrImageIsNotPresent)
+
+	r.NoError(c.Close(ctx))
+}
+
+func TestContainerPullReporter(t *testing.T) {
+	r := require.New(t)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Minute)
+	defer cancel()
+
+	events := []PullProgress{}
+	c, err := NewContainer(
+		"test-pull-reporter",
+		images.Memcache,
+		nil,
+		NewEnvironment(),
+		NewPortBindings(),
+		WithPullPolicy(PullAlways),
+		WithPullReporter(PullReporterFunc(func(p PullProgress) {
+			events = append(events, p)
+		})),
+	)
+	r.NoError(err)
+
+	RunContainer(t, ctx, c)
+
+	r.NotEmpty(events)
+	r.Equal(images.Memcache, events[0].Image)
+}
+
+func TestContainerPlatform(t *testing.T) {
+	r := require.New(t)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Minute)
+	defer cancel()
+
+	c, err := NewContainer(
+		"test-container-platform",
+		images.Memcache,
+		nil,
+		NewEnvironment(),
+		NewPortBindings(),
+		WithPlatform("linux/amd64"),
+	)
+	r.NoError(err)
+	r.Empty(c.Platform())
+
+	RunContainer(t, ctx, c)
+
+	r.Equal("linux/amd64", c.Platform())
+}
+
+func TestContainerRegistryMirrorFallback(t *testing.T) {
+	r := require.New(t)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Minute)
+	defer cancel()
+
+	t.Setenv("REGISTRY_MIRRORS", "docker.io=localhost:1")
+
+	c, err := NewContainer(
+		"test-registry-mirror-fallback",
+		images.Memcache,
+		nil,
+		NewEnvironment(),
+		NewPortBindings(),
+		WithPullPolicy(PullAlways),
+	)
+	r.NoError(err)
+	r.Equal("localhost:1/library/memcached:1.6.29-alpine3.20", c.(*container).image)
+
+	RunContainer(t, ctx, c)
+
+	r.Equal(images.Memcache, c.(*container).image)
+}
+
+func TestContainerImageCache(t *testing.T) {
+	r := require.New(t)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Minute)
+	defer cancel()
+
+	c, err := NewContainer("test-image-cache-pull", images.Alpine, []string{"sleep", "300"}, NewEnvironment(), NewPortBindings())
+	r.NoError(err)
+	RunContainer(t, ctx, c)
+
+	cli := c.(*container).cli
+
+	// the registry is unreachable so the image could only be loaded from the cache
+	ref := "localhost:1/go-docker-testsuite-cache:" + random.String(random.AlphaLower, 8)
+	r.NoError(cli.ImageTag(ctx, images.Alpine, ref))
+
+	dir := t.TempDir()
+	name, err := ImageCacheFileName(ref)
+	r.NoError(err)
+
+	rc, err := cli.ImageSave(ctx, []string{ref})
+	r.NoError(err)
+	data, err := io.ReadAll(rc)
+	r.NoError(err)
+	r.NoError(rc.Close())
+	r.NoError(os.WriteFile(filepath.Join(dir, name), data, 0o644))
+
+	_, err = cli.ImageRemove(ctx, ref, image.RemoveOptions{})
+	r.NoError(err)
+	t.Cleanup(func() {
+		_, _ = cli.ImageRemove(context.Background(), ref, image.RemoveOptions{})
+	})
+
+	t.Setenv("IMAGE_CACHE_DIR", dir)
+
+	c, err = NewContainer("test-image-cache", ref, []string{"sleep", "300"}, NewEnvironment(), NewPortBindings(), WithPullPolicy(PullIfNotPresent))
+	r.NoError(err)
+	RunContainer(t, ctx, c)
+}
+
+func TestContainerImageDigestMismatch(t *testing.T) {
+	r := require.New(t)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Minute)
+	defer cancel()
+
+	c, err := NewContainer(
+		"test-image-digest-mismatch",
+		images.Memcache,
+		nil,
+		NewEnvironment(),
+		NewPortBindings(),
+		WithImageDigest("sha256:0000000000000000000000000000000000000000000000000000000000000000"),
+	)
+	r.NoError(err)
+
+	err = c.Run(ctx)
+	r.ErrorIs(err, ErrImageDigestMismatch)
+
+	r.NoError(c.Close(ctx))
+}
+
+func TestContainerInspect(t *testing.T) {
+	r := require.New(t)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Minute)
+	defer cancel()
+
+	c, err := NewContainer("test-inspect", images.Memcache, nil, NewEnvironment(), NewPortBindings())
+	r.NoError(err)
+
+	_, err = c.Inspect(ctx)
+	r.ErrorIs(err, ErrContainerIsNotRunning)
+
+	RunContainer(t, ctx, c)
+
+	res, err := c.Inspect(ctx)
+	r.NoError(err)
+	r.Equal(c.ID(), res.ID)
+	r.Equal("running", res.Status)
+	r.True(res.Running)
+	r.False(res.OOMKilled)
+	r.False(res.StartedAt.IsZero())
+	r.True(res.FinishedAt.IsZero())
+	r.Equal(c.Platform(), res.Platform)
+	r.NotEmpty(res.Networks)
+}
+
+func TestContainerRunToCompletion(t *testing.T) {
+	r := require.New(t)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Minute)
+	defer cancel()
+
+	c, err := NewContainer("test-job", images.Alpine, []string{"sh", "-c", "echo done"}, NewEnvironment(), NewPortBindings())
+	r.NoError(err)
+	defer func() { r.NoError(c.Close(ctx)) }()
+
+	res, err := RunToCompletion(ctx, c)
+	r.NoError(err)
+	r.Equal(0, res.ExitCode)
+	r.Equal("done\n", string(res.Stdout))
+	r.Empty(res.Stderr)
+
+	failing, err := NewContainer("test-job-failing", images.Alpine, []string{"sh", "-c", "echo out; echo failed >&2; exit 3"}, NewEnvironment(), NewPortBindings())
+	r.NoError(err)
+	defer func() { r.NoError(failing.Close(ctx)) }()
+
+	res, err = RunToCompletion(ctx, failing)
+	r.ErrorIs(err, ErrNonZeroExitCode)
+	r.Contains(err.Error(), "failed")
+	r.Equal(3, res.ExitCode)
+	r.Equal("out\n", string(res.Stdout))
+	r.Equal("failed\n", string(res.Stderr))
+
+	// Wait returns the same result once the container is exited
+	res, err = failing.Wait(ctx)
+	r.NoError(err)
+	r.Equal(3, res.ExitCode)
+}
+
+func TestContainerRunToCompletionReuse(t *testing.T) {
+	r := require.New(t)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Minute)
+	defer cancel()
+
+	t.Setenv("CONTAINER_REUSE", "true")
+
+	c, err := NewContainer("test-job-reuse", images.Alpine, []string{"true"}, NewEnvironment(), NewPortBindings())
+	r.NoError(err)
+
+	_, err = RunToCompletion(ctx, c)
+	r.NoError(err)
+
+	r.NoError(c.Close(ctx))
+
+	_, err = c.(*container).cli.ContainerInspect(ctx, string(c.ID()))
+	r.True(cerrdefs.IsNotFound(err), "exited job container is expected to be removed: %v", err)
+}
@@ -0,0 +1,189 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	dockerContainer "github.com/docker/docker/api/types/container"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// CopyTo copies the content read from src into the container as the file
+// at dstPath with the given mode. Missing parent directories are created.
+// When called before Run() the file is copied right after the container
+// is created and before it is started.
+func (c *container) CopyTo(ctx context.Context, src io.Reader, dstPath string, mode os.FileMode) error {
+	name, err := archiveName(dstPath)
+	if err != nil {
+		return err
+	}
+
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return errors.Wrap(err, "error reading source content")
+	}
+
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	err = tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     int64(mode.Perm()),
+		Size:     int64(len(data)),
+		ModTime:  time.Now(),
+	})
+	if err != nil {
+		return errors.Wrap(err, "error writing archive header")
+	}
+
+	if _, err := tw.Write(data); err != nil {
+		return errors.Wrap(err, "error writing archive content")
+	}
+
+	if err := tw.Close(); err != nil {
+		return errors.Wrap(err, "error finalizing archive")
+	}
+
+	return c.copyArchive(ctx, buf.Bytes())
+}
+
+// CopyPathTo copies the file or the directory (recursively) from the host
+// srcPath into the container at dstPath preserving file modes. When called
+// before Run() the content is copied right after the container is created
+// and before it is started.
+func (c *container) CopyPathTo(ctx context.Context, srcPath, dstPath string) error {
+	name, err := archiveName(dstPath)
+	if err != nil {
+		return err
+	}
+
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	err = filepath.WalkDir(srcPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(srcPath, p)
+		if err != nil {
+			return err
+		}
+
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		link := ""
+		if fi.Mode()&os.ModeSymlink != 0 {
+			link, err = os.Readlink(p)
+			if err != nil {
+				return err
+			}
+		}
+
+		hdr, err := tar.FileInfoHeader(fi, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = path.Join(name, filepath.ToSlash(rel))
+		hdr.Uid, hdr.Gid = 0, 0
+		hdr.Uname, hdr.Gname = "", ""
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+
+		fp, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = fp.Close() }()
+
+		_, err = io.Copy(tw, fp)
+		return err
+	})
+	if err != nil {
+		return errors.Wrapf(err, "error archiving `%s`", srcPath)
+	}
+
+	if err := tw.Close(); err != nil {
+		return errors.Wrap(err, "error finalizing archive")
+	}
+
+	return c.copyArchive(ctx, buf.Bytes())
+}
+
+// CopyFrom reads the path from the container. For regular files the returned
+// reader yields the file content, for directories it yields the tar archive
+// of the directory as produced by Docker.
+func (c *container) CopyFrom(ctx context.Context, srcPath string) (io.ReadCloser, error) {
+	if c.containerID == "" {
+		return nil, ErrContainerIsNotRunning
+	}
+
+	rc, stat, err := c.cli.CopyFromContainer(ctx, c.containerID, srcPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error copying `%s` from container", srcPath)
+	}
+
+	if !stat.Mode.IsRegular() {
+		return rc, nil
+	}
+
+	tr := tar.NewReader(rc)
+	if _, err := tr.Next(); err != nil {
+		_ = rc.Close()
+		return nil, errors.Wrap(err, "error reading archive header")
+	}
+
+	return &readCloser{
+		Reader: tr,
+		Closer: rc,
+	}, nil
+}
+
+func (c *container) copyArchive(ctx context.Context, archive []byte) error {
+	if c.containerID == "" {
+		log.WithFields(log.Fields{
+			"name": c.name,
+			"size": len(archive),
+		}).Trace("container is not created yet: postponing copy")
+
+		c.pendingCopies = append(c.pendingCopies, archive)
+		return nil
+	}
+
+	err := c.cli.CopyToContainer(ctx, c.containerID, "/", bytes.NewReader(archive), dockerContainer.CopyToContainerOptions{})
+	return errors.Wrap(err, "error copying to container")
+}
+
+func archiveName(dstPath string) (string, error) {
+	if !path.IsAbs(dstPath) {
+		return "", errors.Errorf("destination path `%s` must be absolute", dstPath)
+	}
+
+	name := strings.TrimPrefix(path.Clean(dstPath), "/")
+	if name == "" {
+		return "", errors.New("destination path must not be the root directory")
+	}
+	return name, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,26 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiveName(t *testing.T) {
+	r := require.New(t)
+
+	name, err := archiveName("/etc/app/config.yaml")
+	r.NoError(err)
+	r.Equal("etc/app/config.yaml", name)
+
+	name, err = archiveName("/docker-entrypoint-initdb.d/../init.sql")
+	r.NoError(err)
+	r.Equal("init.sql", name)
+
+	_, err = archiveName("relative/path")
+	r.Error(err)
+	r.Equal("destination path `relative/path` must be absolute", err.Error())
+
+	_, err = archiveName("/")
+	r.Error(err)
+}
@@ -0,0 +1,98 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	dockerContainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+var ErrContainerIsNotRunning = errors.New("container is not running")
+
+// ExecOptions allows to customize the command executed inside the container
+type ExecOptions struct {
+	// Env is passed to the command in addition to the container environment
+	Env Environment
+	// WorkingDir overrides the working directory of the command
+	WorkingDir string
+	// User overrides the user the command runs as (user[:group])
+	User string
+	// Stdin is streamed to the command standard input when set
+	Stdin io.Reader
+}
+
+// ExecResult holds the outcome of the command executed inside the container
+type ExecResult struct {
+	ExitCode int
+	Stdout   []byte
+	Stderr   []byte
+}
+
+// Exec runs the command inside the running container and returns its
+// demultiplexed output and exit code. Non-zero exit code is not an error:
+// callers are expected to check ExitCode on their own.
+func (c *container) Exec(ctx context.Context, cmd []string, opts *ExecOptions) (*ExecResult, error) {
+	if c.containerID == "" {
+		return nil, ErrContainerIsNotRunning
+	}
+
+	if opts == nil {
+		opts = &ExecOptions{}
+	}
+
+	log.WithFields(log.Fields{
+		"container": c.containerID,
+		"cmd":       cmd,
+	}).Trace("executing command in container via exec")
+
+	execResp, err := c.cli.ContainerExecCreate(ctx, c.containerID, dockerContainer.ExecOptions{
+		User:         opts.User,
+		AttachStdin:  opts.Stdin != nil,
+		AttachStdout: true,
+		AttachStderr: true,
+		Env:          opts.Env.Eval(newContainerInfoFromContainer(c)),
+		WorkingDir:   opts.WorkingDir,
+		Cmd:          cmd,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating exec instance")
+	}
+
+	attachResp, err := c.cli.ContainerExecAttach(ctx, execResp.ID, dockerContainer.ExecAttachOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "error attaching to exec")
+	}
+	defer attachResp.Close()
+
+	if opts.Stdin != nil {
+		go func() {
+			_, err := io.Copy(attachResp.Conn, opts.Stdin)
+			if err != nil {
+				log.WithError(err).Warn("error streaming stdin to exec")
+			}
+			_ = attachResp.CloseWrite()
+		}()
+	}
+
+	// Docker multiplexes stdout and stderr into a single stream with headers.
+	var stdoutBuf, stderrBuf bytes.Buffer
+	_, err = stdcopy.StdCopy(&stdoutBuf, &stderrBuf, attachResp.Reader)
+	if err != nil {
+		return nil, errors.Wrap(err, "error demuxing exec output")
+	}
+
+	inspectResp, err := c.cli.ContainerExecInspect(ctx, execResp.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error inspecting exec result")
+	}
+
+	return &ExecResult{
+		ExitCode: inspectResp.ExitCode,
+		Stdout:   stdoutBuf.Bytes(),
+		Stderr:   stderrBuf.Bytes(),
+	}, nil
+}
@@ -0,0 +1,199 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/teran/go-docker-testsuite/images"
+)
+
+const (
+	goImageCACertsPath     = "/etc/ssl/certs/ca-certificates.crt"
+	goImageDefaultBinary   = "app"
+	goImageBinaryDirectory = "/usr/local/bin"
+)
+
+// GoBuildOptions describes the Go package to build into the image
+type GoBuildOptions struct {
+	// Package is the import path or the path relative to Dir of the main
+	// package, i.e. "./cmd/server"
+	Package string
+	// Dir is the directory `go build` is run in, current directory by default
+	Dir string
+	// BaseImage is the image the binary is put on top of. By default the
+	// image is built from scratch with CA certificates only.
+	BaseImage string
+	// GOARCH is the architecture to build for, the architecture of the
+	// Docker daemon by default
+	GOARCH string
+	// Flags are passed to `go build` as is, i.e. "-tags=integration"
+	Flags []string
+	// Tag is the additional reference the image is tagged with
+	Tag string
+}
+
+// BuildGoImage compiles the Go package with CGO_ENABLED=0 GOOS=linux and
+// builds the image with the binary as its entrypoint. The image is built
+// with BuildImage() so it's tagged with the hash of the binary and the
+// base image and reused while the code is unchanged.
+func BuildGoImage(ctx context.Context, opts *GoBuildOptions) (string, error) {
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return "", err
+	}
+
+	return BuildGoImageWithClient(ctx, cli, opts)
+}
+
+// BuildGoImageWithClient builds the image the same way as BuildGoImage and
+// allows to pass custom docker.Client instance
+func BuildGoImageWithClient(ctx context.Context, cli *client.Client, opts *GoBuildOptions) (string, error) {
+	goarch := opts.GOARCH
+	if goarch == "" {
+		v, err := cli.ServerVersion(ctx)
+		if err != nil {
+			return "", errors.Wrap(err, "error retrieving Docker daemon version")
+		}
+		goarch = v.Arch
+	}
+
+	binary, err := opts.compile(ctx, goarch)
+	if err != nil {
+		return "", err
+	}
+
+	buildCtx, err := opts.context(binary)
+	if err != nil {
+		return "", err
+	}
+
+	return BuildImageWithClient(ctx, cli, &BuildOptions{
+		Context: bytes.NewReader(buildCtx),
+		Tag:     opts.Tag,
+	})
+}
+
+func (o *GoBuildOptions) compile(ctx context.Context, goarch string) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "go-docker-testsuite-build-")
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating temporary directory")
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	out := filepath.Join(dir, o.binaryName())
+
+	args := append([]string{"build", "-trimpath", "-buildvcs=false", "-o", out}, o.Flags...)
+	args = append(args, o.Package)
+
+	log.WithFields(log.Fields{
+		"package": o.Package,
+		"dir":     o.Dir,
+		"goarch":  goarch,
+		"args":    args,
+	}).Debug("compiling Go package")
+
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = o.Dir
+	cmd.Env = append(os.Environ(), "CGO_ENABLED=0", "GOOS=linux", "GOARCH="+goarch)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, errors.Wrapf(err, "error compiling `%s`: %s", o.Package, strings.TrimSpace(string(output)))
+	}
+
+	data, err := os.ReadFile(out)
+	return data, errors.Wrap(err, "error reading compiled binary")
+}
+
+// binaryName returns the name of the binary after the last element
+// of the package path
+func (o *GoBuildOptions) binaryName() string {
+	name := path.Base(filepath.ToSlash(strings.TrimRight(o.Package, "/")))
+	if name == "." || name == "/" || name == "" || strings.HasPrefix(name, ".") {
+		return goImageDefaultBinary
+	}
+	return name
+}
+
+func (o *GoBuildOptions) dockerfile() (string, error) {
+	binary := path.Join(goImageBinaryDirectory, o.binaryName())
+
+	lines := []string{}
+	if o.BaseImage == "" {
+		certs, err := imageReference(images.Alpine)
+		if err != nil {
+			return "", err
+		}
+
+		lines = append(lines,
+			"FROM "+certs+" AS certs",
+			"FROM scratch",
+			fmt.Sprintf("COPY --from=certs %s %s", goImageCACertsPath, goImageCACertsPath),
+		)
+	} else {
+		base, err := imageReference(o.BaseImage)
+		if err != nil {
+			return "", err
+		}
+
+		lines = append(lines, "FROM "+base)
+	}
+
+	return strings.Join(append(lines,
+		fmt.Sprintf("COPY %s %s", o.binaryName(), binary),
+		fmt.Sprintf("ENTRYPOINT [%q]", binary),
+	), "\n") + "\n", nil
+}
+
+// context returns the build context archive with the Dockerfile and
+// the binary
+func (o *GoBuildOptions) context(binary []byte) ([]byte, error) {
+	dockerfile, err := o.dockerfile()
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+
+	for _, f := range []struct {
+		name string
+		mode int64
+		data []byte
+	}{
+		{defaultDockerfile, 0o644, []byte(dockerfile)},
+		{o.binaryName(), 0o755, binary},
+	} {
+		err := tw.WriteHeader(&tar.Header{
+			Name:    f.name,
+			Mode:    f.mode,
+			Size:    int64(len(f.data)),
+			ModTime: time.Unix(0, 0),
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "error writing `%s` header", f.name)
+		}
+
+		if _, err := tw.Write(f.data); err != nil {
+			return nil, errors.Wrapf(err, "error writing `%s`", f.name)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, errors.Wrap(err, "error finalizing build context archive")
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,80 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoBuildBinaryName(t *testing.T) {
+	r := require.New(t)
+
+	r.Equal("server", (&GoBuildOptions{Package: "./cmd/server"}).binaryName())
+	r.Equal("server", (&GoBuildOptions{Package: "github.com/example/app/cmd/server/"}).binaryName())
+	r.Equal("app", (&GoBuildOptions{Package: "."}).binaryName())
+	r.Equal("app", (&GoBuildOptions{}).binaryName())
+}
+
+func TestGoBuildDockerfile(t *testing.T) {
+	r := require.New(t)
+
+	t.Setenv("IMAGE_PREFIX", "")
+
+	dockerfile, err := (&GoBuildOptions{Package: "./cmd/server"}).dockerfile()
+	r.NoError(err)
+	r.Equal(`FROM index.docker.io/library/alpine:3.20 AS certs
+FROM scratch
+COPY --from=certs /etc/ssl/certs/ca-certificates.crt /etc/ssl/certs/ca-certificates.crt
+COPY server /usr/local/bin/server
+ENTRYPOINT ["/usr/local/bin/server"]
+`, dockerfile)
+
+	t.Setenv("IMAGE_PREFIX", "mirror.example.com")
+
+	dockerfile, err = (&GoBuildOptions{Package: "./cmd/server", BaseImage: "debian:12"}).dockerfile()
+	r.NoError(err)
+	r.Equal(`FROM mirror.example.com/debian:12
+COPY server /usr/local/bin/server
+ENTRYPOINT ["/usr/local/bin/server"]
+`, dockerfile)
+}
+
+func TestGoBuildCompile(t *testing.T) {
+	r := require.New(t)
+
+	opts := &GoBuildOptions{Package: "./testdata/hello"}
+
+	b1, err := opts.compile(t.Context(), "amd64")
+	r.NoError(err)
+	r.Equal([]byte("\x7fELF"), b1[:4])
+
+	// Unchanged code produces the same binary and so the same build context
+	b2, err := opts.compile(t.Context(), "amd64")
+	r.NoError(err)
+	r.Equal(b1, b2)
+
+	ctx1, err := opts.context(b1)
+	r.NoError(err)
+
+	ctx2, err := opts.context(b2)
+	r.NoError(err)
+	r.Equal(ctx1, ctx2)
+
+	names := []string{}
+	tr := tar.NewReader(bytes.NewReader(ctx1))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		r.NoError(err)
+		names = append(names, hdr.Name)
+	}
+	r.Equal([]string{"Dockerfile", "hello"}, names)
+
+	_, err = (&GoBuildOptions{Package: "./testdata/missing"}).compile(t.Context(), "amd64")
+	r.Error(err)
+}
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
@@ -13,9 +12,25 @@ import (
 	"github.com/teran/go-docker-testsuite/internal/random"
 )
 
+var ErrGroupIsNotRunning = errors.New("group is not running")
+
 type Group interface {
 	Run(ctx context.Context) error
 	Close(ctx context.Context) error
+	Containers() []Container
+
+	// RunToCompletion runs the one-shot container in the group network and
+	// waits for it to exit
+	RunToCompletion(ctx context.Context, c Container) (*WaitResult, error)
+
+	// Disconnect disconnects the application from the group network
+	Disconnect(ctx context.Context, name string) error
+	// Reconnect connects the application back keeping its alias and address
+	Reconnect(ctx context.Context, name string) error
+	// Partition splits the applications into isolated subsets
+	Partition(ctx context.Context, subsets ...[]string) error
+	// Heal reverts Partition() and reconnects disconnected applications
+	Heal(ctx context.Context) error
 }
 
 type group struct {
@@ -24,6 +39,9 @@ type group struct {
 
 	cli       *client.Client
 	networkID string
+
+	disconnected      map[string]string
+	partitionNetworks []NetworkID
 }
 
 func NewGroup(name string, apps ...*Application) (Group, error) {
@@ -40,9 +58,21 @@ func NewGroupWithClient(cli *client.Client, name string, apps ...*Application) (
 		name: fmt.Sprintf("%s-%s", name, random.String(random.AlphaNumeric, 14)),
 		apps: apps,
 		cli:  cli,
+
+		disconnected: make(map[string]string),
 	}, nil
 }
 
+// Containers returns containers of the group applications in the order
+// they're run
+func (g *group) Containers() []Container {
+	out := make([]Container, 0, len(g.apps))
+	for _, app := range g.apps {
+		out = append(out, app.container)
+	}
+	return out
+}
+
 func (g *group) Close(ctx context.Context) error {
 	var errs []error
 
@@ -65,6 +95,11 @@ func (g *group) Close(ctx context.Context) error {
 		}
 	}
 
+	if err := g.removePartitionNetworks(ctx); err != nil {
+		log.WithError(err).Error("error removing partition networks")
+		errs = append(errs, err)
+	}
+
 	if err := g.cli.NetworkRemove(ctx, g.networkID); err != nil {
 		log.WithError(err).Errorf("error removing network %s", g.networkID)
 		errs = append(errs, err)
@@ -81,19 +116,18 @@ func (g *group) Close(ctx context.Context) error {
 }
 
 func (g *group) Run(ctx context.Context) error {
+	reapOrphansOnce(ctx, g.cli)
+
 	log.WithFields(log.Fields{
 		"name": g.name,
 	}).Trace("creating network")
 
-	net, err := g.cli.NetworkCreate(ctx, g.name, network.CreateOptions{
-		Attachable: true,
-		Internal:   true,
-	})
+	networkID, err := g.createNetwork(ctx, g.name)
 	if err != nil {
 		return err
 	}
 
-	g.networkID = net.ID
+	g.networkID = networkID
 
 	log.WithFields(log.Fields{
 		"name": g.name,
@@ -125,6 +159,23 @@ func (g *group) Run(ctx context.Context) error {
 	return nil
 }
 
+// RunToCompletion runs the one-shot container (i.e. migrations) in the
+// network of the running group, so it reaches the applications by their
+// names, and waits for it to exit. The container is closed with the group.
+func (g *group) RunToCompletion(ctx context.Context, c Container) (*WaitResult, error) {
+	if g.networkID == "" {
+		return nil, ErrGroupIsNotRunning
+	}
+
+	if err := c.NetworkAttach(g.networkID); err != nil {
+		return nil, errors.Wrapf(err, "error attaching to network `%s`", g.networkID)
+	}
+
+	g.apps = append(g.apps, NewApplication(c))
+
+	return RunToCompletion(ctx, c)
+}
+
 func runHooks(ctx context.Context, app *Application, ht HookType) error {
 	if len(app.hooks) > 0 {
 		for _, h := range app.hooks {
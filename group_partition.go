@@ -0,0 +1,290 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/api/types/network"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrAppNotFound          = errors.New("application is not found in the group")
+	ErrAppIsNotDisconnected = errors.New("application is not disconnected from the group network")
+	ErrAlreadyPartitioned   = errors.New("group is already partitioned")
+)
+
+// networkCreateAttempts is the amount of attempts to create the network
+// with the probed subnet taken by someone else in the meantime
+const networkCreateAttempts = 5
+
+// networkCreateMu serializes the network creation within the process so
+// the groups don't race for the same probed subnet
+var networkCreateMu sync.Mutex
+
+// createNetwork creates internal network with the subnet explicitly set:
+// Docker allows to assign static addresses (required to keep the address
+// on reconnect) only on networks with user configured subnets, so the network
+// is created first to let Docker pick up free subnet and then recreated with it.
+// The subnet could be taken by another group or process between the probe
+// removal and the creation, so the creation is retried with new probe.
+func (g *group) createNetwork(ctx context.Context, name string) (NetworkID, error) {
+	networkCreateMu.Lock()
+	defer networkCreateMu.Unlock()
+
+	for attempt := 1; ; attempt++ {
+		id, err := g.createNetworkOnce(ctx, name)
+		if err == nil {
+			return id, nil
+		}
+
+		if attempt >= networkCreateAttempts || !isPoolOverlapError(err) {
+			return "", err
+		}
+
+		log.WithFields(log.Fields{
+			"name":    name,
+			"attempt": attempt,
+		}).WithError(err).Debug("probed subnet is taken: retrying network creation")
+	}
+}
+
+func (g *group) createNetworkOnce(ctx context.Context, name string) (NetworkID, error) {
+	opts := network.CreateOptions{
+		Attachable: true,
+		Internal:   true,
+		Labels:     sessionLabels(),
+	}
+
+	probe, err := g.cli.NetworkCreate(ctx, name, opts)
+	if err != nil {
+		return "", err
+	}
+
+	info, err := g.cli.NetworkInspect(ctx, probe.ID, network.InspectOptions{})
+	if err != nil {
+		if rmErr := g.cli.NetworkRemove(ctx, probe.ID); rmErr != nil {
+			log.WithFields(log.Fields{
+				"id": probe.ID,
+			}).WithError(rmErr).Warn("error removing probe network")
+		}
+		return "", errors.Wrap(err, "error inspecting network")
+	}
+
+	if err := g.cli.NetworkRemove(ctx, probe.ID); err != nil {
+		return "", errors.Wrap(err, "error removing network")
+	}
+
+	opts.IPAM = &network.IPAM{
+		Driver: info.IPAM.Driver,
+		Config: info.IPAM.Config,
+	}
+
+	net, err := g.cli.NetworkCreate(ctx, name, opts)
+	if err != nil {
+		return "", err
+	}
+
+	log.WithFields(log.Fields{
+		"name":   name,
+		"id":     net.ID,
+		"subnet": info.IPAM.Config,
+	}).Trace("network created with explicit subnet")
+
+	return net.ID, nil
+}
+
+// isPoolOverlapError reports whether the network creation is failed since
+// the subnet is already used by another network
+func isPoolOverlapError(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), "pool overlaps")
+}
+
+func (g *group) app(name string) (*Application, error) {
+	for _, app := range g.apps {
+		if app.container.Name() == name {
+			return app, nil
+		}
+	}
+	return nil, errors.Wrapf(ErrAppNotFound, "`%s`", name)
+}
+
+// address returns the address of the container in the group network
+func (g *group) address(ctx context.Context, c Container) (string, error) {
+	info, err := g.cli.ContainerInspect(ctx, c.ID())
+	if err != nil {
+		return "", errors.Wrap(err, "error inspecting container")
+	}
+
+	if info.NetworkSettings != nil {
+		for _, ep := range info.NetworkSettings.Networks {
+			if ep.NetworkID == g.networkID {
+				return ep.IPAddress, nil
+			}
+		}
+	}
+	return "", errors.Errorf("container `%s` is not connected to the group network", c.Name())
+}
+
+// Disconnect disconnects the application from the group network so
+// it can't reach and can't be reached by the other applications
+func (g *group) Disconnect(ctx context.Context, name string) error {
+	app, err := g.app(name)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := g.disconnected[name]; ok {
+		return nil
+	}
+
+	addr, err := g.address(ctx, app.container)
+	if err != nil {
+		return err
+	}
+
+	log.WithFields(log.Fields{
+		"name":    name,
+		"address": addr,
+	}).Debug("disconnecting app from the group network")
+
+	err = g.cli.NetworkDisconnect(ctx, g.networkID, app.container.ID(), true)
+	if err != nil {
+		return errors.Wrapf(err, "error disconnecting `%s` from network", name)
+	}
+
+	g.disconnected[name] = addr
+
+	return nil
+}
+
+// Reconnect connects the application disconnected with Disconnect back
+// to the group network keeping its alias and address
+func (g *group) Reconnect(ctx context.Context, name string) error {
+	app, err := g.app(name)
+	if err != nil {
+		return err
+	}
+
+	addr, ok := g.disconnected[name]
+	if !ok {
+		return errors.Wrapf(ErrAppIsNotDisconnected, "`%s`", name)
+	}
+
+	log.WithFields(log.Fields{
+		"name":    name,
+		"address": addr,
+	}).Debug("reconnecting app to the group network")
+
+	err = g.cli.NetworkConnect(ctx, g.networkID, app.container.ID(), &network.EndpointSettings{
+		Aliases: []string{name},
+		IPAMConfig: &network.EndpointIPAMConfig{
+			IPv4Address: addr,
+		},
+	})
+	if err != nil {
+		return errors.Wrapf(err, "error connecting `%s` to network", name)
+	}
+
+	delete(g.disconnected, name)
+
+	return nil
+}
+
+// Partition splits the applications into the subsets (by the application
+// names) which can't reach each other: applications of the same subset
+// are still reachable by their aliases. Applications not listed in any subset
+// stay together in the group network. Addresses of the listed applications
+// are changed until Heal() is called.
+func (g *group) Partition(ctx context.Context, subsets ...[]string) error {
+	if len(g.partitionNetworks) > 0 {
+		return ErrAlreadyPartitioned
+	}
+
+	seen := map[string]struct{}{}
+	for _, subset := range subsets {
+		for _, name := range subset {
+			if _, err := g.app(name); err != nil {
+				return err
+			}
+
+			if _, ok := seen[name]; ok {
+				return errors.Errorf("application `%s` is listed in more than one subset", name)
+			}
+			seen[name] = struct{}{}
+		}
+	}
+
+	for i, subset := range subsets {
+		netName := fmt.Sprintf("%s-partition-%d", g.name, i)
+		net, err := g.cli.NetworkCreate(ctx, netName, network.CreateOptions{
+			Attachable: true,
+			Internal:   true,
+			Labels:     sessionLabels(),
+		})
+		if err != nil {
+			return errors.Wrapf(err, "error creating network `%s`", netName)
+		}
+		g.partitionNetworks = append(g.partitionNetworks, net.ID)
+
+		for _, name := range subset {
+			if err := g.Disconnect(ctx, name); err != nil {
+				return err
+			}
+
+			app, _ := g.app(name)
+			err := g.cli.NetworkConnect(ctx, net.ID, app.container.ID(), &network.EndpointSettings{
+				Aliases: []string{name},
+			})
+			if err != nil {
+				return errors.Wrapf(err, "error connecting `%s` to network `%s`", name, netName)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Heal reverts Partition(): all of the applications are connected back
+// to the group network with their original addresses
+func (g *group) Heal(ctx context.Context) error {
+	if err := g.removePartitionNetworks(ctx); err != nil {
+		return err
+	}
+
+	for name := range g.disconnected {
+		if err := g.Reconnect(ctx, name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (g *group) removePartitionNetworks(ctx context.Context) error {
+	for len(g.partitionNetworks) > 0 {
+		netID := g.partitionNetworks[0]
+
+		info, err := g.cli.NetworkInspect(ctx, netID, network.InspectOptions{})
+		if err != nil {
+			return errors.Wrap(err, "error inspecting network")
+		}
+
+		for containerID := range info.Containers {
+			err := g.cli.NetworkDisconnect(ctx, netID, containerID, true)
+			if err != nil {
+				return errors.Wrapf(err, "error disconnecting `%s` from network", containerID)
+			}
+		}
+
+		if err := g.cli.NetworkRemove(ctx, netID); err != nil {
+			return errors.Wrapf(err, "error removing network `%s`", netID)
+		}
+
+		g.partitionNetworks = g.partitionNetworks[1:]
+	}
+	return nil
+}
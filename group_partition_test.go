@@ -0,0 +1,46 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	log.SetLevel(log.TraceLevel)
+}
+
+func TestGroupPartitionUnknownApp(t *testing.T) {
+	r := require.New(t)
+
+	c := newContainerForHostPort(t, 8080, "8080")
+
+	g, err := NewGroupWithClient(nil, "test", NewApplication(c))
+	r.NoError(err)
+
+	err = g.Disconnect(t.Context(), "unknown")
+	r.ErrorIs(err, ErrAppNotFound)
+
+	err = g.Reconnect(t.Context(), "unknown")
+	r.ErrorIs(err, ErrAppNotFound)
+
+	err = g.Reconnect(t.Context(), "test")
+	r.ErrorIs(err, ErrAppIsNotDisconnected)
+
+	err = g.Partition(t.Context(), []string{"test"}, []string{"unknown"})
+	r.ErrorIs(err, ErrAppNotFound)
+
+	err = g.Partition(t.Context(), []string{"test"}, []string{"test"})
+	r.Error(err)
+	r.Equal("application `test` is listed in more than one subset", err.Error())
+}
+
+func TestIsPoolOverlapError(t *testing.T) {
+	r := require.New(t)
+
+	r.True(isPoolOverlapError(errors.New("Error response from daemon: Pool overlaps with other one on this address space")))
+	r.False(isPoolOverlapError(errors.New("Error response from daemon: network with name test already exists")))
+}
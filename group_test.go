@@ -2,6 +2,8 @@ package docker
 
 import (
 	"context"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -82,3 +84,132 @@ func TestGroup(t *testing.T) {
 	r.Equal("test message", resp.GetMessage())
 
 }
+
+func TestGroupPartition(t *testing.T) {
+	r := require.New(t)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Minute)
+	defer cancel()
+
+	names := []string{"node-a", "node-b", "node-c"}
+	apps := []*Application{}
+	containers := map[string]Container{}
+	for _, name := range names {
+		c, err := NewContainer(
+			name,
+			images.Memcache,
+			nil,
+			NewEnvironment(),
+			NewPortBindings(),
+		)
+		r.NoError(err)
+
+		containers[name] = c
+		apps = append(apps, NewApplication(c))
+	}
+
+	g, err := NewGroup("test-partition", apps...)
+	r.NoError(err)
+
+	defer func() { _ = g.Close(ctx) }()
+
+	err = g.Run(ctx)
+	r.NoError(err)
+
+	reachable := func(from, to string) bool {
+		res, err := containers[from].Exec(ctx, []string{"nc", "-z", "-w", "2", to, "11211"}, nil)
+		r.NoError(err)
+		return res.ExitCode == 0
+	}
+
+	addr := func(name string) string {
+		res, err := containers[name].Exec(ctx, []string{"hostname", "-i"}, nil)
+		r.NoError(err)
+		return strings.TrimSpace(string(res.Stdout))
+	}
+
+	r.True(reachable("node-a", "node-b"))
+	addrB := addr("node-b")
+
+	err = g.Disconnect(ctx, "node-b")
+	r.NoError(err)
+	r.False(reachable("node-a", "node-b"))
+
+	err = g.Reconnect(ctx, "node-b")
+	r.NoError(err)
+	r.True(reachable("node-a", "node-b"))
+	r.Equal(addrB, addr("node-b"))
+
+	err = g.Partition(ctx, []string{"node-a", "node-b"}, []string{"node-c"})
+	r.NoError(err)
+	r.True(reachable("node-a", "node-b"))
+	r.False(reachable("node-a", "node-c"))
+	r.False(reachable("node-c", "node-b"))
+
+	err = g.Heal(ctx)
+	r.NoError(err)
+	r.True(reachable("node-a", "node-c"))
+	r.True(reachable("node-c", "node-b"))
+	r.Equal(addrB, addr("node-b"))
+}
+
+func TestGroupInspect(t *testing.T) {
+	r := require.New(t)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Minute)
+	defer cancel()
+
+	c, err := NewContainer("memcache", images.Memcache, nil, NewEnvironment(), NewPortBindings())
+	r.NoError(err)
+
+	g, err := NewGroup("test-inspect", NewApplication(c))
+	r.NoError(err)
+
+	defer func() { _ = g.Close(ctx) }()
+
+	err = g.Run(ctx)
+	r.NoError(err)
+
+	res, err := c.Inspect(ctx)
+	r.NoError(err)
+
+	var ep *NetworkEndpoint
+	for _, v := range res.Networks {
+		if slices.Contains(v.Aliases, "memcache") {
+			ep = &v
+		}
+	}
+	r.NotNil(ep, "group network endpoint with the application alias is expected: %v", res.Networks)
+	r.NotEmpty(ep.IPAddress)
+}
+
+func TestGroupRunToCompletion(t *testing.T) {
+	r := require.New(t)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Minute)
+	defer cancel()
+
+	c, err := NewContainer("memcache", images.Memcache, nil, NewEnvironment(), NewPortBindings())
+	r.NoError(err)
+
+	g, err := NewGroup("test-job", NewApplication(c))
+	r.NoError(err)
+
+	job, err := NewContainer("job", images.Alpine, []string{
+		"sh", "-c", "for i in $(seq 30); do nc -z -w 2 memcache 11211 && exit 0; sleep 1; done; exit 1",
+	}, NewEnvironment(), NewPortBindings())
+	r.NoError(err)
+
+	_, err = g.RunToCompletion(ctx, job)
+	r.ErrorIs(err, ErrGroupIsNotRunning)
+
+	defer func() { _ = g.Close(ctx) }()
+
+	err = g.Run(ctx)
+	r.NoError(err)
+
+	res, err := g.RunToCompletion(ctx, job)
+	r.NoError(err)
+	r.Equal(0, res.ExitCode)
+	r.Len(g.Containers(), 2)
+}
@@ -0,0 +1,108 @@
+package docker
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	dockerContainer "github.com/docker/docker/api/types/container"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrContainerExited      = errors.New("container exited")
+	ErrContainerIsUnhealthy = errors.New("container is unhealthy")
+	ErrNoHealthcheck        = errors.New("container has no healthcheck defined")
+)
+
+// Healthcheck describes the Docker HEALTHCHECK of the container
+type Healthcheck struct {
+	// Test is the command to check the container health. It could be in
+	// Docker form ({"CMD", "pg_isready"} or {"CMD-SHELL", "pg_isready || exit 1"})
+	// or just the command with arguments which is run as CMD.
+	Test []string
+	// Interval is the time between running the checks
+	Interval time.Duration
+	// Timeout is the time to wait before considering the check to have hung
+	Timeout time.Duration
+	// StartPeriod is the time for the container to initialize before
+	// failed checks start to count against retries
+	StartPeriod time.Duration
+	// Retries is the number of consecutive failures needed to consider
+	// the container as unhealthy
+	Retries int
+}
+
+func (h *Healthcheck) healthConfig() *dockerContainer.HealthConfig {
+	if h == nil {
+		return nil
+	}
+
+	test := h.Test
+	if len(test) > 0 {
+		switch test[0] {
+		case "CMD", "CMD-SHELL", "NONE":
+		default:
+			test = append([]string{"CMD"}, test...)
+		}
+	}
+
+	return &dockerContainer.HealthConfig{
+		Test:        test,
+		Interval:    h.Interval,
+		Timeout:     h.Timeout,
+		StartPeriod: h.StartPeriod,
+		Retries:     h.Retries,
+	}
+}
+
+// SetHealthcheck sets the health check for the container overriding the
+// one defined by the image. Must be called before Run().
+func (c *container) SetHealthcheck(hc *Healthcheck) {
+	c.healthcheck = hc
+}
+
+// AwaitHealthy blocks until Docker reports the container as healthy. It
+// fails as soon as the container exits or is reported as unhealthy.
+func (c *container) AwaitHealthy(ctx context.Context) error {
+	return poll(ctx, func(ctx context.Context) error {
+		info, err := c.inspect(ctx)
+		if err != nil {
+			return err
+		}
+
+		if info.State == nil {
+			return errors.New("container state is not available")
+		}
+
+		if !info.State.Running {
+			return permanentError{errors.Wrapf(ErrContainerExited, "exit code %d", info.State.ExitCode)}
+		}
+
+		if info.State.Health == nil {
+			return permanentError{ErrNoHealthcheck}
+		}
+
+		log.WithFields(log.Fields{
+			"name":   c.name,
+			"status": info.State.Health.Status,
+		}).Trace("container health status retrieved")
+
+		switch info.State.Health.Status {
+		case dockerContainer.Healthy:
+			return nil
+		case dockerContainer.Unhealthy:
+			return permanentError{errors.Wrapf(ErrContainerIsUnhealthy, "last check output: %s", lastHealthcheckOutput(info.State.Health))}
+		default:
+			return errors.Errorf("container health status is `%s`", info.State.Health.Status)
+		}
+	})
+}
+
+func lastHealthcheckOutput(h *dockerContainer.Health) string {
+	if len(h.Log) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(h.Log[len(h.Log)-1].Output)
+}
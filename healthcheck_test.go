@@ -0,0 +1,36 @@
+package docker
+
+import (
+	"testing"
+	"time"
+
+	dockerContainer "github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthcheckHealthConfig(t *testing.T) {
+	r := require.New(t)
+
+	var hc *Healthcheck
+	r.Nil(hc.healthConfig())
+
+	hc = &Healthcheck{
+		Test:        []string{"pg_isready", "-U", "postgres"},
+		Interval:    1 * time.Second,
+		Timeout:     2 * time.Second,
+		StartPeriod: 3 * time.Second,
+		Retries:     4,
+	}
+	r.Equal(&dockerContainer.HealthConfig{
+		Test:        []string{"CMD", "pg_isready", "-U", "postgres"},
+		Interval:    1 * time.Second,
+		Timeout:     2 * time.Second,
+		StartPeriod: 3 * time.Second,
+		Retries:     4,
+	}, hc.healthConfig())
+
+	hc = &Healthcheck{
+		Test: []string{"CMD-SHELL", "pg_isready || exit 1"},
+	}
+	r.Equal([]string{"CMD-SHELL", "pg_isready || exit 1"}, hc.healthConfig().Test)
+}
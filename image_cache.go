@@ -0,0 +1,103 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/client"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// imageCacheDirEnvVar sets the directory with the image tarballs
+	// (produced by `docker save`) loaded instead of pulling
+	imageCacheDirEnvVar = "IMAGE_CACHE_DIR"
+
+	imageCacheFileExt = ".tar"
+)
+
+// ImageCacheFileName returns the name of the tarball the image is looked up
+// by in IMAGE_CACHE_DIR: the normalized reference with path separators,
+// tag and digest delimiters replaced, i.e.
+// "docker.io_library_postgres_16.3.tar" for "postgres:16.3"
+func ImageCacheFileName(image string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", errors.Wrapf(err, "error parsing image reference `%s`", image)
+	}
+
+	name := strings.NewReplacer("/", "_", ":", "_", "@", "_").Replace(reference.TagNameOnly(named).String())
+	return name + imageCacheFileExt, nil
+}
+
+// cachedImagePath returns the path to the tarball of the image in
+// IMAGE_CACHE_DIR if it exists
+func cachedImagePath(image string) (string, bool) {
+	dir := os.Getenv(imageCacheDirEnvVar)
+	if dir == "" {
+		return "", false
+	}
+
+	name, err := ImageCacheFileName(image)
+	if err != nil {
+		return "", false
+	}
+
+	path := filepath.Join(dir, name)
+	if _, err := os.Stat(path); err != nil {
+		return "", false
+	}
+	return path, true
+}
+
+// loadCachedImage loads the image tarball from IMAGE_CACHE_DIR and reports
+// whether the tarball for the image is found
+func loadCachedImage(ctx context.Context, cli *client.Client, image string) (bool, error) {
+	path, ok := cachedImagePath(image)
+	if !ok {
+		return false, nil
+	}
+
+	log.WithFields(log.Fields{
+		"image": image,
+		"path":  path,
+	}).Debug("loading image from the cache directory")
+
+	fp, err := os.Open(path)
+	if err != nil {
+		return false, errors.Wrapf(err, "error opening image tarball `%s`", path)
+	}
+	defer func() { _ = fp.Close() }()
+
+	resp, err := cli.ImageLoad(ctx, fp, client.ImageLoadWithQuiet(true))
+	if err != nil {
+		return false, errors.Wrapf(err, "error loading image tarball `%s`", path)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if !resp.JSON {
+		_, err := io.Copy(io.Discard, resp.Body)
+		return true, errors.Wrapf(err, "error loading image tarball `%s`", path)
+	}
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		msg := jsonMessage{}
+		if err := dec.Decode(&msg); err != nil {
+			if err == io.EOF {
+				return true, nil
+			}
+			return false, errors.Wrapf(err, "error loading image tarball `%s`", path)
+		}
+
+		if err := msg.err(); err != nil {
+			return false, errors.Wrapf(err, "error loading image tarball `%s`", path)
+		}
+	}
+}
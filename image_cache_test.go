@@ -0,0 +1,49 @@
+package docker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageCacheFileName(t *testing.T) {
+	r := require.New(t)
+
+	for image, expected := range map[string]string{
+		"postgres":                              "docker.io_library_postgres_latest.tar",
+		"postgres:16.3":                         "docker.io_library_postgres_16.3.tar",
+		"index.docker.io/library/postgres:16.3": "docker.io_library_postgres_16.3.tar",
+		"ghcr.io/teran/echo-grpc-server:latest": "ghcr.io_teran_echo-grpc-server_latest.tar",
+		"localhost:5000/app:v1":                 "localhost_5000_app_v1.tar",
+		"ghcr.io/teran/app@sha256:0123456789012345678901234567890123456789012345678901234567890123": "ghcr.io_teran_app_sha256_0123456789012345678901234567890123456789012345678901234567890123.tar",
+	} {
+		name, err := ImageCacheFileName(image)
+		r.NoError(err)
+		r.Equal(expected, name, image)
+	}
+
+	_, err := ImageCacheFileName("Invalid Reference")
+	r.Error(err)
+}
+
+func TestCachedImagePath(t *testing.T) {
+	r := require.New(t)
+
+	dir := t.TempDir()
+	r.NoError(os.WriteFile(filepath.Join(dir, "docker.io_library_postgres_16.3.tar"), []byte("tar"), 0o644))
+
+	t.Setenv("IMAGE_CACHE_DIR", "")
+	_, ok := cachedImagePath("postgres:16.3")
+	r.False(ok)
+
+	t.Setenv("IMAGE_CACHE_DIR", dir)
+
+	path, ok := cachedImagePath("index.docker.io/library/postgres:16.3")
+	r.True(ok)
+	r.Equal(filepath.Join(dir, "docker.io_library_postgres_16.3.tar"), path)
+
+	_, ok = cachedImagePath("postgres:17.4")
+	r.False(ok)
+}
@@ -0,0 +1,100 @@
+package docker
+
+import (
+	"context"
+	"sync"
+
+	"github.com/distribution/reference"
+	"github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/teran/go-docker-testsuite/images"
+)
+
+var ErrImageDigestMismatch = errors.New("image digest doesn't match the pinned one")
+
+// lockedDigests are the digests pinned in images.lock by the normalized
+// image references
+var lockedDigests = sync.OnceValue(func() map[string]digest.Digest {
+	digests := map[string]digest.Digest{}
+	for ref, d := range images.Digests() {
+		named, err := reference.ParseNormalizedNamed(ref)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"image": ref,
+				"error": err,
+			}).Warn("invalid image reference in images lock file: skipping")
+			continue
+		}
+		digests[reference.TagNameOnly(named).String()] = digest.Digest(d)
+	}
+	return digests
+})
+
+// WithImageDigest pins the digest (i.e. "sha256:...") of the image: the image
+// is verified against it after pull and Run() fails on mismatch. By default
+// the digests pinned in the images package lock file are used.
+func WithImageDigest(d string) ContainerOption {
+	return func(cc *ContainerConfig) {
+		cc.ImageDigest = d
+	}
+}
+
+// pinnedDigest returns the digest the image is pinned to with
+// WithImageDigest() or in the images lock file, empty digest means
+// the image is not pinned
+func pinnedDigest(cc *ContainerConfig, image string) (digest.Digest, error) {
+	if cc.ImageDigest != "" {
+		d, err := digest.Parse(cc.ImageDigest)
+		return d, errors.Wrapf(err, "invalid image digest `%s`", cc.ImageDigest)
+	}
+
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", errors.Wrapf(err, "error parsing image reference `%s`", image)
+	}
+
+	// The image referenced by the digest is verified by Docker on pull
+	if _, ok := named.(reference.Digested); ok {
+		return "", nil
+	}
+
+	return lockedDigests()[reference.TagNameOnly(named).String()], nil
+}
+
+// verifyImageDigest checks the repository digests of the local image contain
+// the pinned one. Images without repository digests (i.e. loaded from
+// IMAGE_CACHE_DIR with the classic image store) couldn't be verified and
+// are accepted with a warning.
+func (c *container) verifyImageDigest(ctx context.Context, ref string, expected digest.Digest) error {
+	info, err := c.cli.ImageInspect(ctx, ref)
+	if err != nil {
+		return errors.Wrapf(err, "error inspecting image `%s`", ref)
+	}
+
+	if len(info.RepoDigests) == 0 {
+		log.WithFields(log.Fields{
+			"image":  ref,
+			"digest": expected,
+		}).Warn("image has no repository digests: pinned digest is not verified")
+		return nil
+	}
+
+	for _, rd := range info.RepoDigests {
+		named, err := reference.ParseNormalizedNamed(rd)
+		if err != nil {
+			continue
+		}
+
+		if canonical, ok := named.(reference.Canonical); ok && canonical.Digest() == expected {
+			log.WithFields(log.Fields{
+				"image":  ref,
+				"digest": expected,
+			}).Trace("image digest verified")
+			return nil
+		}
+	}
+
+	return errors.Wrapf(ErrImageDigestMismatch, "`%s`: expected %s, got %v", ref, expected, info.RepoDigests)
+}
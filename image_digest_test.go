@@ -0,0 +1,41 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPinnedDigest(t *testing.T) {
+	r := require.New(t)
+
+	locked := lockedDigests
+	defer func() { lockedDigests = locked }()
+
+	lockedDigests = func() map[string]digest.Digest {
+		return map[string]digest.Digest{
+			"docker.io/library/postgres:16.3": "sha256:1111111111111111111111111111111111111111111111111111111111111111",
+		}
+	}
+
+	for image, expected := range map[string]digest.Digest{
+		"index.docker.io/library/postgres:16.3": "sha256:1111111111111111111111111111111111111111111111111111111111111111",
+		"postgres:16.3":                         "sha256:1111111111111111111111111111111111111111111111111111111111111111",
+		"postgres:17.4":                         "",
+		"postgres@sha256:2222222222222222222222222222222222222222222222222222222222222222": "",
+	} {
+		d, err := pinnedDigest(&ContainerConfig{}, image)
+		r.NoError(err)
+		r.Equal(expected, d, image)
+	}
+
+	cc := &ContainerConfig{}
+	WithImageDigest("sha256:3333333333333333333333333333333333333333333333333333333333333333")(cc)
+	d, err := pinnedDigest(cc, "postgres:16.3")
+	r.NoError(err)
+	r.Equal(digest.Digest("sha256:3333333333333333333333333333333333333333333333333333333333333333"), d)
+
+	_, err = pinnedDigest(&ContainerConfig{ImageDigest: "sha256:invalid"}, "postgres:16.3")
+	r.Error(err)
+}
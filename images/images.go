@@ -24,4 +24,10 @@ const (
 
 	// K3s image tag
 	K3s = "index.docker.io/rancher/k3s:v1.36.2-k3s1"
+
+	// Netshoot image tag (provides tc for network fault injection)
+	Netshoot = "index.docker.io/nicolaka/netshoot:v0.13"
+
+	// Alpine image tag (provides CA certificates for images built from scratch)
+	Alpine = "index.docker.io/library/alpine:3.20"
 )
@@ -0,0 +1,38 @@
+package images
+
+import (
+	_ "embed"
+	"strings"
+)
+
+//go:embed images.lock
+var lockFile string
+
+// LockFile is the name of the file with the pinned digests in the package
+// directory
+const LockFile = "images.lock"
+
+// Digests returns the pinned digests from images.lock by the image
+// references as they are written there
+func Digests() map[string]string {
+	return ParseLock(lockFile)
+}
+
+// ParseLock parses the lock file content: `<reference> <digest>` per line,
+// empty lines and `#` comments are skipped
+func ParseLock(s string) map[string]string {
+	digests := map[string]string{}
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		digests[fields[0]] = fields[1]
+	}
+	return digests
+}
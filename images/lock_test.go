@@ -0,0 +1,27 @@
+package images
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDigests(t *testing.T) {
+	r := require.New(t)
+
+	lines := 0
+	for _, line := range strings.Split(lockFile, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			lines++
+		}
+	}
+
+	digests := Digests()
+	r.Len(digests, lines, "malformed lines in %s", LockFile)
+
+	for ref, d := range digests {
+		r.True(strings.HasPrefix(d, "sha256:"), ref)
+	}
+}
@@ -0,0 +1,149 @@
+package docker
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	dockerContainer "github.com/docker/docker/api/types/container"
+	"github.com/pkg/errors"
+)
+
+// InspectResult is the snapshot of the container runtime state
+type InspectResult struct {
+	ID       ContainerID
+	Name     string
+	Image    string
+	Platform string
+
+	// Status is the container status reported by Docker: "created",
+	// "running", "paused", "restarting", "removing", "exited" or "dead"
+	Status     string
+	Running    bool
+	Paused     bool
+	Restarting bool
+	OOMKilled  bool
+	// ExitCode is only meaningful once the container is exited
+	ExitCode int
+	// Error is the error Docker failed to start the container with
+	Error string
+
+	// StartedAt and FinishedAt are zero if the container has never been
+	// started or finished
+	StartedAt  time.Time
+	FinishedAt time.Time
+
+	// Health is the health check status: "starting", "healthy" or
+	// "unhealthy", empty if the container has no health check
+	Health string
+
+	// Networks are the endpoints of the container by the network name
+	Networks map[string]NetworkEndpoint
+}
+
+// NetworkEndpoint describes the container endpoint on the attached network
+type NetworkEndpoint struct {
+	NetworkID   string
+	IPAddress   string
+	IPv6Address string
+	Gateway     string
+	MacAddress  string
+	// Aliases are the names the container is reachable by from the other
+	// containers on the network, i.e. the application name in the Group
+	Aliases []string
+}
+
+// Inspect returns the snapshot of the container state, the exit code and
+// the network addresses. It is only available after Run() is called.
+func (c *container) Inspect(ctx context.Context) (*InspectResult, error) {
+	info, err := c.inspect(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "error inspecting container")
+	}
+
+	res, err := newInspectResult(info)
+	if err != nil {
+		return nil, err
+	}
+
+	res.Platform = c.platform
+	return res, nil
+}
+
+func newInspectResult(info dockerContainer.InspectResponse) (*InspectResult, error) {
+	res := &InspectResult{
+		Networks: map[string]NetworkEndpoint{},
+	}
+
+	if info.ContainerJSONBase == nil {
+		return res, nil
+	}
+
+	res.ID = ContainerID(info.ID)
+	res.Name = strings.TrimPrefix(info.Name, "/")
+	if info.Config != nil {
+		res.Image = info.Config.Image
+	}
+
+	if st := info.State; st != nil {
+		res.Status = st.Status
+		res.Running = st.Running
+		res.Paused = st.Paused
+		res.Restarting = st.Restarting
+		res.OOMKilled = st.OOMKilled
+		res.ExitCode = st.ExitCode
+		res.Error = st.Error
+
+		var err error
+		res.StartedAt, err = parseStateTime(st.StartedAt)
+		if err != nil {
+			return nil, errors.Wrap(err, "error parsing container start time")
+		}
+
+		res.FinishedAt, err = parseStateTime(st.FinishedAt)
+		if err != nil {
+			return nil, errors.Wrap(err, "error parsing container finish time")
+		}
+
+		if st.Health != nil {
+			res.Health = st.Health.Status
+		}
+	}
+
+	if info.NetworkSettings != nil {
+		for name, ep := range info.NetworkSettings.Networks {
+			if ep == nil {
+				continue
+			}
+
+			res.Networks[name] = NetworkEndpoint{
+				NetworkID:   ep.NetworkID,
+				IPAddress:   ep.IPAddress,
+				IPv6Address: ep.GlobalIPv6Address,
+				Gateway:     ep.Gateway,
+				MacAddress:  ep.MacAddress,
+				Aliases:     ep.Aliases,
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// parseStateTime parses the time reported by Docker, the zero time
+// ("0001-01-01T00:00:00Z") and empty string are returned as zero time
+func parseStateTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if t.IsZero() {
+		return time.Time{}, nil
+	}
+	return t, nil
+}
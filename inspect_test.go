@@ -0,0 +1,88 @@
+package docker
+
+import (
+	"testing"
+	"time"
+
+	dockerContainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewInspectResult(t *testing.T) {
+	r := require.New(t)
+
+	res, err := newInspectResult(dockerContainer.InspectResponse{
+		ContainerJSONBase: &dockerContainer.ContainerJSONBase{
+			ID:   "abc123",
+			Name: "/test-container",
+			State: &dockerContainer.State{
+				Status:     "exited",
+				OOMKilled:  true,
+				ExitCode:   137,
+				StartedAt:  "2026-10-18T10:00:00.5Z",
+				FinishedAt: "2026-10-18T10:01:00Z",
+				Health: &dockerContainer.Health{
+					Status: dockerContainer.Unhealthy,
+				},
+			},
+		},
+		Config: &dockerContainer.Config{
+			Image: "index.docker.io/library/memcached:1.6.29-alpine3.20",
+		},
+		NetworkSettings: &dockerContainer.NetworkSettings{
+			Networks: map[string]*network.EndpointSettings{
+				"test-group-net": {
+					NetworkID: "net123",
+					IPAddress: "172.18.0.2",
+					Gateway:   "172.18.0.1",
+					Aliases:   []string{"memcache"},
+				},
+				"broken": nil,
+			},
+		},
+	})
+	r.NoError(err)
+	r.Equal(&InspectResult{
+		ID:         "abc123",
+		Name:       "test-container",
+		Image:      "index.docker.io/library/memcached:1.6.29-alpine3.20",
+		Status:     "exited",
+		OOMKilled:  true,
+		ExitCode:   137,
+		StartedAt:  time.Date(2026, 10, 18, 10, 0, 0, 500000000, time.UTC),
+		FinishedAt: time.Date(2026, 10, 18, 10, 1, 0, 0, time.UTC),
+		Health:     "unhealthy",
+		Networks: map[string]NetworkEndpoint{
+			"test-group-net": {
+				NetworkID: "net123",
+				IPAddress: "172.18.0.2",
+				Gateway:   "172.18.0.1",
+				Aliases:   []string{"memcache"},
+			},
+		},
+	}, res)
+
+	res, err = newInspectResult(dockerContainer.InspectResponse{
+		ContainerJSONBase: &dockerContainer.ContainerJSONBase{
+			State: &dockerContainer.State{
+				Status:     "created",
+				StartedAt:  "0001-01-01T00:00:00Z",
+				FinishedAt: "0001-01-01T00:00:00Z",
+			},
+		},
+	})
+	r.NoError(err)
+	r.True(res.StartedAt.IsZero())
+	r.True(res.FinishedAt.IsZero())
+	r.Empty(res.Health)
+
+	_, err = newInspectResult(dockerContainer.InspectResponse{
+		ContainerJSONBase: &dockerContainer.ContainerJSONBase{
+			State: &dockerContainer.State{
+				StartedAt: "yesterday",
+			},
+		},
+	})
+	r.Error(err)
+}
@@ -0,0 +1,133 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"strings"
+
+	dockerContainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// jobOutputTailLines is the amount of the last output lines included into
+// the error of the job exited with non-zero code
+const jobOutputTailLines = 20
+
+var ErrNonZeroExitCode = errors.New("container exited with non-zero code")
+
+// WaitResult holds the outcome of the container process
+type WaitResult struct {
+	ExitCode  int
+	OOMKilled bool
+	Stdout    []byte
+	Stderr    []byte
+}
+
+// Wait blocks until the container process exits and returns its exit code
+// and the whole output. Non-zero exit code is not an error: callers are
+// expected to check ExitCode on their own or use RunToCompletion().
+func (c *container) Wait(ctx context.Context) (*WaitResult, error) {
+	if c.containerID == "" {
+		return nil, ErrContainerIsNotRunning
+	}
+
+	log.WithFields(log.Fields{
+		"name": c.name,
+	}).Trace("waiting for container to exit")
+
+	statusCh, errCh := c.cli.ContainerWait(ctx, c.containerID, dockerContainer.WaitConditionNotRunning)
+	select {
+	case <-ctx.Done():
+		return nil, errors.Wrapf(ctx.Err(), "error waiting for container `%s` to exit", c.name)
+	case err := <-errCh:
+		return nil, errors.Wrapf(err, "error waiting for container `%s` to exit", c.name)
+	case status := <-statusCh:
+		if status.Error != nil && status.Error.Message != "" {
+			return nil, errors.Errorf("error waiting for container `%s` to exit: %s", c.name, status.Error.Message)
+		}
+	}
+
+	info, err := c.inspect(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "error inspecting container")
+	}
+
+	res := &WaitResult{
+		ExitCode:  info.State.ExitCode,
+		OOMKilled: info.State.OOMKilled,
+	}
+
+	rd, err := c.cli.ContainerLogs(ctx, c.containerID, dockerContainer.LogsOptions{
+		ShowStderr: true,
+		ShowStdout: true,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading container output")
+	}
+	defer func() { _ = rd.Close() }()
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	if info.Config != nil && info.Config.Tty {
+		_, err = stdout.ReadFrom(rd)
+	} else {
+		_, err = stdcopy.StdCopy(stdout, stderr, rd)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading container output")
+	}
+
+	res.Stdout, res.Stderr = stdout.Bytes(), stderr.Bytes()
+
+	log.WithFields(log.Fields{
+		"name":      c.name,
+		"exit_code": res.ExitCode,
+	}).Debug("container exited")
+
+	return res, nil
+}
+
+// RunToCompletion runs the one-shot container (i.e. migrations or a CLI
+// tool), waits for it to exit and returns ErrNonZeroExitCode along with
+// the result if the exit code is not zero. The container is not closed.
+// Reuse mode is always disabled for the job.
+func RunToCompletion(ctx context.Context, c Container) (*WaitResult, error) {
+	// Jobs exit so there's nothing to reuse, the lookup could also take over
+	// the running container with the same configuration
+	c.SetReuse(false)
+
+	if err := c.Run(ctx); err != nil {
+		return nil, err
+	}
+
+	return waitForCompletion(ctx, c)
+}
+
+func waitForCompletion(ctx context.Context, c Container) (*WaitResult, error) {
+	res, err := c.Wait(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.ExitCode != 0 {
+		return res, errors.Wrapf(ErrNonZeroExitCode, "`%s` exited with code %d (OOM killed: %t):\n%s",
+			c.Name(), res.ExitCode, res.OOMKilled, outputTail(res, jobOutputTailLines))
+	}
+	return res, nil
+}
+
+// outputTail returns the last n lines of stderr or of stdout if stderr
+// is empty
+func outputTail(res *WaitResult, n int) string {
+	out := res.Stderr
+	if len(bytes.TrimSpace(out)) == 0 {
+		out = res.Stdout
+	}
+
+	lines := strings.Split(strings.TrimRight(string(out), "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
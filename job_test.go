@@ -0,0 +1,23 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutputTail(t *testing.T) {
+	r := require.New(t)
+
+	r.Equal("err2\nerr3", outputTail(&WaitResult{
+		Stdout: []byte("out1\nout2\n"),
+		Stderr: []byte("err1\nerr2\nerr3\n"),
+	}, 2))
+
+	r.Equal("out1\nout2", outputTail(&WaitResult{
+		Stdout: []byte("out1\nout2\n"),
+		Stderr: []byte("\n"),
+	}, 5))
+
+	r.Empty(outputTail(&WaitResult{}, 5))
+}
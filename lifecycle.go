@@ -0,0 +1,149 @@
+package docker
+
+import (
+	"context"
+	"time"
+
+	dockerContainer "github.com/docker/docker/api/types/container"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/teran/go-docker-testsuite/internal/ptr"
+)
+
+// Lifecycle allows to control the state of the running container without
+// removing it, i.e. to test how services handle restarts or frozen peers.
+// Host port mappings are preserved so URL() stays valid.
+type Lifecycle interface {
+	Kill(ctx context.Context, signal string) error
+	Pause(ctx context.Context) error
+	Restart(ctx context.Context) error
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+	Unpause(ctx context.Context) error
+}
+
+// Stop stops the container keeping it so it could be started again
+// with Start()
+func (c *container) Stop(ctx context.Context) error {
+	if c.containerID == "" {
+		return ErrContainerIsNotRunning
+	}
+
+	log.WithFields(log.Fields{
+		"name": c.name,
+		"id":   c.containerID,
+	}).Trace("stopping container")
+
+	err := c.cli.ContainerStop(ctx, c.containerID, c.stopOptions(ctx))
+	if err != nil {
+		return errors.Wrap(err, "error stopping container")
+	}
+
+	c.stopLogs(true)
+
+	return nil
+}
+
+// Start starts previously stopped container and awaits its readiness with
+// the wait strategy. Output produced before the start is not considered by
+// AwaitOutput() anymore.
+func (c *container) Start(ctx context.Context) error {
+	if c.containerID == "" {
+		return ErrContainerIsNotRunning
+	}
+
+	log.WithFields(log.Fields{
+		"name": c.name,
+		"id":   c.containerID,
+	}).Trace("starting container")
+
+	err := c.cli.ContainerStart(ctx, c.containerID, dockerContainer.StartOptions{})
+	if err != nil {
+		return errors.Wrap(err, "error starting container")
+	}
+
+	info, err := c.inspect(ctx)
+	if err != nil {
+		return errors.Wrap(err, "error inspecting container")
+	}
+
+	if info.State != nil {
+		c.startedAt = info.State.StartedAt
+	}
+
+	if !c.logsFollowing() {
+		c.stopLogs(false)
+
+		if err := c.followLogs(c.startedAt); err != nil {
+			return err
+		}
+	}
+
+	return c.waitUntilReady(ctx)
+}
+
+// Restart stops and starts the container again
+func (c *container) Restart(ctx context.Context) error {
+	if err := c.Stop(ctx); err != nil {
+		return err
+	}
+	return c.Start(ctx)
+}
+
+// Pause freezes all of the container processes
+func (c *container) Pause(ctx context.Context) error {
+	if c.containerID == "" {
+		return ErrContainerIsNotRunning
+	}
+
+	log.WithFields(log.Fields{
+		"name": c.name,
+		"id":   c.containerID,
+	}).Trace("pausing container")
+
+	return errors.Wrap(c.cli.ContainerPause(ctx, c.containerID), "error pausing container")
+}
+
+// Unpause resumes the container processes frozen by Pause()
+func (c *container) Unpause(ctx context.Context) error {
+	if c.containerID == "" {
+		return ErrContainerIsNotRunning
+	}
+
+	log.WithFields(log.Fields{
+		"name": c.name,
+		"id":   c.containerID,
+	}).Trace("unpausing container")
+
+	return errors.Wrap(c.cli.ContainerUnpause(ctx, c.containerID), "error unpausing container")
+}
+
+// Kill sends the signal (i.e. "SIGKILL", "SIGHUP") to the container main
+// process. The container could be started again with Start() once it's exited.
+func (c *container) Kill(ctx context.Context, signal string) error {
+	if c.containerID == "" {
+		return ErrContainerIsNotRunning
+	}
+
+	log.WithFields(log.Fields{
+		"name":   c.name,
+		"id":     c.containerID,
+		"signal": signal,
+	}).Trace("sending signal to container")
+
+	return errors.Wrapf(c.cli.ContainerKill(ctx, c.containerID, signal), "error sending `%s` to container", signal)
+}
+
+// stopTimeout returns the time in seconds the container is given to stop
+// gracefully: the time left until the context deadline or
+// defaultStopTimeout if there's no deadline
+func stopTimeout(ctx context.Context) *int {
+	timeout := defaultStopTimeout
+	if dl, ok := ctx.Deadline(); ok {
+		if remaining := time.Until(dl); remaining > 0 {
+			timeout = remaining
+		}
+	}
+	return ptr.Ptr[int](int(timeout / time.Second))
+}
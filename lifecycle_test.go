@@ -0,0 +1,40 @@
+package docker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	log.SetLevel(log.TraceLevel)
+}
+
+func TestStopTimeout(t *testing.T) {
+	r := require.New(t)
+
+	r.Equal(int(defaultStopTimeout/time.Second), *stopTimeout(t.Context()))
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+
+	v := *stopTimeout(ctx)
+	r.LessOrEqual(v, 10)
+	r.GreaterOrEqual(v, 9)
+}
+
+func TestLifecycleNotRunning(t *testing.T) {
+	r := require.New(t)
+
+	c := newContainerForHostPort(t, 8080, "8080")
+
+	r.ErrorIs(c.Stop(t.Context()), ErrContainerIsNotRunning)
+	r.ErrorIs(c.Start(t.Context()), ErrContainerIsNotRunning)
+	r.ErrorIs(c.Restart(t.Context()), ErrContainerIsNotRunning)
+	r.ErrorIs(c.Pause(t.Context()), ErrContainerIsNotRunning)
+	r.ErrorIs(c.Unpause(t.Context()), ErrContainerIsNotRunning)
+	r.ErrorIs(c.Kill(t.Context(), "SIGKILL"), ErrContainerIsNotRunning)
+}
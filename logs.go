@@ -0,0 +1,354 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	dockerContainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	logsDrainTimeout = 5 * time.Second
+)
+
+// LogStream is the stream the container output line is written to
+type LogStream string
+
+const (
+	// LogStreamStdout ...
+	LogStreamStdout LogStream = "stdout"
+
+	// LogStreamStderr ...
+	LogStreamStderr LogStream = "stderr"
+)
+
+// String returns the string representation of the stream
+func (s LogStream) String() string {
+	return string(s)
+}
+
+// LogEntry is the single line of the container output
+type LogEntry struct {
+	Stream    LogStream
+	Timestamp time.Time
+	Line      string
+}
+
+// LogConsumer receives container output lines as they're produced.
+// Accept is called sequentially from the single goroutine per container.
+type LogConsumer interface {
+	Accept(e LogEntry)
+}
+
+// LogConsumerFunc allows to use plain function as LogConsumer
+type LogConsumerFunc func(e LogEntry)
+
+// Accept implements LogConsumer
+func (f LogConsumerFunc) Accept(e LogEntry) {
+	f(e)
+}
+
+// NewWriterLogConsumer writes every line to the io.Writer prefixed with
+// the timestamp and the stream name
+func NewWriterLogConsumer(w io.Writer) LogConsumer {
+	mu := &sync.Mutex{}
+	return LogConsumerFunc(func(e LogEntry) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		_, _ = fmt.Fprintf(w, "%s %s: %s\n", e.Timestamp.Format(time.RFC3339Nano), e.Stream, e.Line)
+	})
+}
+
+// NewTestingLogConsumer passes every line to t.Log prefixed with the
+// container name so service logs show up alongside the test output.
+//
+// NOTE: the container must be closed before the test completes since
+// testing.TB doesn't allow to log after that.
+func NewTestingLogConsumer(t testing.TB, name string) LogConsumer {
+	return LogConsumerFunc(func(e LogEntry) {
+		t.Logf("[%s] %s: %s", name, e.Stream, e.Line)
+	})
+}
+
+// RingBufferLogConsumer keeps the last N lines of the container output
+type RingBufferLogConsumer struct {
+	mu      sync.Mutex
+	entries []LogEntry
+	next    int
+	full    bool
+}
+
+// NewRingBufferLogConsumer creates new RingBufferLogConsumer keeping up to
+// size last lines
+func NewRingBufferLogConsumer(size int) *RingBufferLogConsumer {
+	if size < 1 {
+		size = 1
+	}
+
+	return &RingBufferLogConsumer{
+		entries: make([]LogEntry, size),
+	}
+}
+
+// Accept implements LogConsumer
+func (b *RingBufferLogConsumer) Accept(e LogEntry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.entries[b.next] = e
+	b.next = (b.next + 1) % len(b.entries)
+	if b.next == 0 {
+		b.full = true
+	}
+}
+
+// Entries returns the kept lines in the order they were received
+func (b *RingBufferLogConsumer) Entries() []LogEntry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.full {
+		return append([]LogEntry{}, b.entries[:b.next]...)
+	}
+	return append(append([]LogEntry{}, b.entries[b.next:]...), b.entries[:b.next]...)
+}
+
+// Lines returns the text of the kept lines in the order they were received
+func (b *RingBufferLogConsumer) Lines() []string {
+	entries := b.Entries()
+	out := make([]string, 0, len(entries))
+	for _, e := range entries {
+		out = append(out, e.Line)
+	}
+	return out
+}
+
+// AddLogConsumer attaches consumers receiving the container output from
+// the start of the container. Must be called before Run().
+func (c *container) AddLogConsumer(lcs ...LogConsumer) {
+	c.logConsumers = append(c.logConsumers, lcs...)
+}
+
+// followLogs starts the goroutine streaming the container output to
+// the log consumers. It's stopped by stopLogs() or when the container stops.
+func (c *container) followLogs(since string) error {
+	if len(c.logConsumers) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	rd, err := c.cli.ContainerLogs(ctx, c.containerID, dockerContainer.LogsOptions{
+		ShowStderr: true,
+		ShowStdout: true,
+		Follow:     true,
+		Timestamps: true,
+		Since:      since,
+	})
+	if err != nil {
+		cancel()
+		return errors.Wrap(err, "error following container logs")
+	}
+
+	done := make(chan struct{})
+	c.logsCancel = cancel
+	c.logsDone = done
+
+	go func() {
+		defer close(done)
+		defer func() { _ = rd.Close() }()
+
+		stdout := newLogLineWriter(LogStreamStdout, c.logConsumers)
+		stderr := newLogLineWriter(LogStreamStderr, c.logConsumers)
+
+		_, err := stdcopy.StdCopy(stdout, stderr, rd)
+		stdout.flush()
+		stderr.flush()
+
+		if err != nil && ctx.Err() == nil {
+			log.WithFields(log.Fields{
+				"name": c.name,
+			}).WithError(err).Warn("error following container logs")
+		}
+	}()
+
+	return nil
+}
+
+// logsFollowing reports whether the output is still being streamed to
+// the consumers
+func (c *container) logsFollowing() bool {
+	if c.logsDone == nil {
+		return false
+	}
+
+	select {
+	case <-c.logsDone:
+		return false
+	default:
+		return true
+	}
+}
+
+// stopLogs stops following the container output. When drain is set it
+// first gives the stream a chance to end on its own (i.e. the container is
+// stopped) so the last lines are passed to the consumers.
+func (c *container) stopLogs(drain bool) {
+	if c.logsCancel == nil {
+		return
+	}
+
+	if drain {
+		select {
+		case <-c.logsDone:
+		case <-time.After(logsDrainTimeout):
+		}
+	}
+
+	c.logsCancel()
+	<-c.logsDone
+
+	c.logsCancel = nil
+	c.logsDone = nil
+}
+
+// tailLogs returns up to n last lines of the container output
+func (c *container) tailLogs(ctx context.Context, n int) ([]LogEntry, error) {
+	if c.containerID == "" {
+		return nil, ErrContainerIsNotRunning
+	}
+
+	rd, err := c.cli.ContainerLogs(ctx, c.containerID, dockerContainer.LogsOptions{
+		ShowStderr: true,
+		ShowStdout: true,
+		Timestamps: true,
+		Tail:       strconv.Itoa(n),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error retrieving container logs")
+	}
+	defer func() { _ = rd.Close() }()
+
+	buf := NewRingBufferLogConsumer(n)
+	stdout := newLogLineWriter(LogStreamStdout, []LogConsumer{buf})
+	stderr := newLogLineWriter(LogStreamStderr, []LogConsumer{buf})
+
+	_, err = stdcopy.StdCopy(stdout, stderr, rd)
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading container logs")
+	}
+	stdout.flush()
+	stderr.flush()
+
+	return buf.Entries(), nil
+}
+
+// startupError is the error of the container which failed to become
+// ready. It carries the last lines of the container output captured before
+// the container is closed by the application constructor, so Start() could
+// dump them.
+type startupError struct {
+	err  error
+	name string
+	logs []LogEntry
+}
+
+func (e *startupError) Error() string {
+	return e.err.Error()
+}
+
+func (e *startupError) Unwrap() error {
+	return e.err
+}
+
+// startupError captures the last lines of the container output into
+// the error
+func (c *container) startupError(err error) error {
+	// The context passed to Run() is likely expired at this point
+	ctx, cancel := context.WithTimeout(context.Background(), defaultStopTimeout)
+	defer cancel()
+
+	entries, logsErr := c.tailLogs(ctx, defaultLogTailLines)
+	if logsErr != nil {
+		log.WithFields(log.Fields{
+			"name": c.name,
+		}).WithError(logsErr).Warn("error capturing container logs on startup failure")
+		return err
+	}
+
+	return &startupError{
+		err:  err,
+		name: c.name,
+		logs: entries,
+	}
+}
+
+// logLineWriter splits the stream into lines with timestamps prepended by
+// Docker and passes them to the consumers
+type logLineWriter struct {
+	stream    LogStream
+	consumers []LogConsumer
+	buf       bytes.Buffer
+}
+
+func newLogLineWriter(stream LogStream, consumers []LogConsumer) *logLineWriter {
+	return &logLineWriter{
+		stream:    stream,
+		consumers: consumers,
+	}
+}
+
+// Write implements io.Writer
+func (w *logLineWriter) Write(p []byte) (int, error) {
+	_, _ = w.buf.Write(p)
+
+	for {
+		i := bytes.IndexByte(w.buf.Bytes(), '\n')
+		if i < 0 {
+			break
+		}
+
+		line := string(w.buf.Next(i + 1))
+		w.emit(strings.TrimRight(line, "\r\n"))
+	}
+
+	return len(p), nil
+}
+
+func (w *logLineWriter) flush() {
+	if w.buf.Len() == 0 {
+		return
+	}
+
+	w.emit(w.buf.String())
+	w.buf.Reset()
+}
+
+func (w *logLineWriter) emit(line string) {
+	e := LogEntry{
+		Stream: w.stream,
+		Line:   line,
+	}
+
+	if ts, rest, ok := strings.Cut(line, " "); ok {
+		if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+			e.Timestamp = t
+			e.Line = rest
+		}
+	}
+
+	for _, lc := range w.consumers {
+		lc.Accept(e)
+	}
+}
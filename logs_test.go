@@ -0,0 +1,88 @@
+package docker
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	log.SetLevel(log.TraceLevel)
+}
+
+func TestLogLineWriter(t *testing.T) {
+	r := require.New(t)
+
+	buf := NewRingBufferLogConsumer(10)
+	w := newLogLineWriter(LogStreamStderr, []LogConsumer{buf})
+
+	_, err := w.Write([]byte("2024-01-02T03:04:05.123456789Z first line\n2024-01-02T03:04:06Z sec"))
+	r.NoError(err)
+	_, err = w.Write([]byte("ond line\r\nno timestamp\n2024-01-02T03:04:07Z \n"))
+	r.NoError(err)
+	_, err = w.Write([]byte("2024-01-02T03:04:08Z unterminated"))
+	r.NoError(err)
+	w.flush()
+
+	r.Equal([]LogEntry{
+		{
+			Stream:    LogStreamStderr,
+			Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 123456789, time.UTC),
+			Line:      "first line",
+		},
+		{
+			Stream:    LogStreamStderr,
+			Timestamp: time.Date(2024, 1, 2, 3, 4, 6, 0, time.UTC),
+			Line:      "second line",
+		},
+		{
+			Stream: LogStreamStderr,
+			Line:   "no timestamp",
+		},
+		{
+			Stream:    LogStreamStderr,
+			Timestamp: time.Date(2024, 1, 2, 3, 4, 7, 0, time.UTC),
+			Line:      "",
+		},
+		{
+			Stream:    LogStreamStderr,
+			Timestamp: time.Date(2024, 1, 2, 3, 4, 8, 0, time.UTC),
+			Line:      "unterminated",
+		},
+	}, buf.Entries())
+}
+
+func TestRingBufferLogConsumer(t *testing.T) {
+	r := require.New(t)
+
+	buf := NewRingBufferLogConsumer(3)
+	r.Empty(buf.Lines())
+
+	for _, l := range []string{"1", "2"} {
+		buf.Accept(LogEntry{Line: l})
+	}
+	r.Equal([]string{"1", "2"}, buf.Lines())
+
+	for _, l := range []string{"3", "4", "5"} {
+		buf.Accept(LogEntry{Line: l})
+	}
+	r.Equal([]string{"3", "4", "5"}, buf.Lines())
+
+	buf.Accept(LogEntry{Line: "6"})
+	r.Equal([]string{"4", "5", "6"}, buf.Lines())
+}
+
+func TestWriterLogConsumer(t *testing.T) {
+	r := require.New(t)
+
+	out := &bytes.Buffer{}
+	NewWriterLogConsumer(out).Accept(LogEntry{
+		Stream:    LogStreamStdout,
+		Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Line:      "test line",
+	})
+	r.Equal("2024-01-02T03:04:05Z stdout: test line\n", out.String())
+}
@@ -0,0 +1,164 @@
+package docker
+
+import (
+	"bufio"
+	"os"
+	"strings"
+
+	"github.com/distribution/reference"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// registryMirrorsEnvVar sets the registry mirror rules,
+	// i.e. "docker.io=mirror.example.com,ghcr.io=ghcr-proxy.example.com"
+	registryMirrorsEnvVar = "REGISTRY_MIRRORS"
+	// registryMirrorsFileEnvVar sets the path to the file with the registry
+	// mirror rules, one per line
+	registryMirrorsFileEnvVar = "REGISTRY_MIRRORS_FILE"
+
+	imagePrefixEnvVar = "IMAGE_PREFIX"
+)
+
+// dockerHubAliases are the registry names which are normalized to docker.io
+var dockerHubAliases = []string{"index.docker.io", "registry-1.docker.io", "registry.hub.docker.com"}
+
+// imageReference returns the reference the image is pulled by. If there's
+// the mirror rule for the registry of the image the reference is normalized
+// (i.e. "postgres" becomes "docker.io/library/postgres:latest") and the
+// registry is replaced with the mirror. Otherwise IMAGE_PREFIX is prepended
+// to the reference if set. Built images are never rewritten.
+func imageReference(image string) (string, error) {
+	if isBuiltImage(image) {
+		return image, nil
+	}
+
+	mirrors, err := loadRegistryMirrors()
+	if err != nil {
+		return "", err
+	}
+
+	if len(mirrors) > 0 {
+		imageRef, ok, err := rewriteImageReference(image, mirrors)
+		if err != nil {
+			return "", err
+		}
+
+		if ok {
+			log.WithFields(log.Fields{
+				"original":  image,
+				"rewritten": imageRef,
+			}).Trace("Rewriting image reference with the registry mirror")
+
+			return imageRef, nil
+		}
+	}
+
+	prefix := os.Getenv(imagePrefixEnvVar)
+	if prefix == "" {
+		return image, nil
+	}
+
+	imageRef := strings.TrimRight(prefix, "/") + "/" + strings.TrimLeft(image, "/")
+	log.WithFields(log.Fields{
+		"original": image,
+		"prefixed": imageRef,
+	}).Trace("Setting prefix for image (for proxy purposes since IMAGE_PREFIX is present)")
+
+	return imageRef, nil
+}
+
+// rewriteImageReference replaces the registry of the image with the mirror
+// if there's the rule for it
+func rewriteImageReference(image string, mirrors map[string]string) (string, bool, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", false, errors.Wrapf(err, "error parsing image reference `%s`", image)
+	}
+
+	mirror, ok := mirrors[reference.Domain(named)]
+	if !ok {
+		return "", false, nil
+	}
+
+	named = reference.TagNameOnly(named)
+
+	imageRef := mirror + "/" + reference.Path(named)
+	if tagged, ok := named.(reference.Tagged); ok {
+		imageRef += ":" + tagged.Tag()
+	}
+	if digested, ok := named.(reference.Digested); ok {
+		imageRef += "@" + digested.Digest().String()
+	}
+
+	return imageRef, true, nil
+}
+
+// loadRegistryMirrors reads the mirror rules from the file set in
+// REGISTRY_MIRRORS_FILE and REGISTRY_MIRRORS environment variable,
+// the latter takes precedence
+func loadRegistryMirrors() (map[string]string, error) {
+	mirrors := map[string]string{}
+
+	if path := os.Getenv(registryMirrorsFileEnvVar); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error reading registry mirrors file `%s`", path)
+		}
+
+		if err := parseRegistryMirrors(string(data), mirrors); err != nil {
+			return nil, errors.Wrapf(err, "error parsing registry mirrors file `%s`", path)
+		}
+	}
+
+	if err := parseRegistryMirrors(os.Getenv(registryMirrorsEnvVar), mirrors); err != nil {
+		return nil, errors.Wrapf(err, "error parsing %s", registryMirrorsEnvVar)
+	}
+
+	return mirrors, nil
+}
+
+// parseRegistryMirrors parses `registry=mirror` rules separated by commas
+// or new lines, lines starting with `#` are ignored
+func parseRegistryMirrors(s string, mirrors map[string]string) error {
+	sc := bufio.NewScanner(strings.NewReader(s))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		for _, rule := range strings.Split(line, ",") {
+			rule = strings.TrimSpace(rule)
+			if rule == "" {
+				continue
+			}
+
+			registry, mirror, ok := strings.Cut(rule, "=")
+			registry, mirror = normalizeRegistry(registry), normalizeRegistry(mirror)
+			if !ok || registry == "" || mirror == "" {
+				return errors.Errorf("invalid rule `%s`: registry=mirror is expected", rule)
+			}
+
+			mirrors[registry] = mirror
+		}
+	}
+	return sc.Err()
+}
+
+// normalizeRegistry strips the scheme and trailing slashes and maps
+// Docker Hub aliases to docker.io
+func normalizeRegistry(s string) string {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(s, "https://")
+	s = strings.TrimPrefix(s, "http://")
+	s = strings.TrimRight(s, "/")
+
+	for _, alias := range dockerHubAliases {
+		if s == alias {
+			return dockerHubDomain
+		}
+	}
+	return s
+}
@@ -0,0 +1,98 @@
+package docker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRegistryMirrors(t *testing.T) {
+	r := require.New(t)
+
+	mirrors := map[string]string{}
+	err := parseRegistryMirrors(`
+# Docker Hub
+index.docker.io=https://mirror.example.com/dockerhub/
+ghcr.io=ghcr-proxy.example.com, quay.io=quay-proxy.example.com
+`, mirrors)
+	r.NoError(err)
+	r.Equal(map[string]string{
+		"docker.io": "mirror.example.com/dockerhub",
+		"ghcr.io":   "ghcr-proxy.example.com",
+		"quay.io":   "quay-proxy.example.com",
+	}, mirrors)
+
+	for _, s := range []string{"docker.io", "docker.io=", "=mirror.example.com"} {
+		r.Error(parseRegistryMirrors(s, map[string]string{}), s)
+	}
+}
+
+func TestRewriteImageReference(t *testing.T) {
+	r := require.New(t)
+
+	mirrors := map[string]string{
+		"docker.io": "mirror.example.com/dockerhub",
+		"ghcr.io":   "ghcr-proxy.example.com",
+	}
+
+	for image, expected := range map[string]string{
+		"postgres":                                 "mirror.example.com/dockerhub/library/postgres:latest",
+		"index.docker.io/library/postgres:16.3":    "mirror.example.com/dockerhub/library/postgres:16.3",
+		"minio/minio:RELEASE.2024-05-10T01-41-38Z": "mirror.example.com/dockerhub/minio/minio:RELEASE.2024-05-10T01-41-38Z",
+		"ghcr.io/teran/echo-grpc-server:latest":    "ghcr-proxy.example.com/teran/echo-grpc-server:latest",
+		"ghcr.io/teran/app@sha256:0123456789012345678901234567890123456789012345678901234567890123": "ghcr-proxy.example.com/teran/app@sha256:0123456789012345678901234567890123456789012345678901234567890123",
+	} {
+		ref, ok, err := rewriteImageReference(image, mirrors)
+		r.NoError(err)
+		r.True(ok, image)
+		r.Equal(expected, ref)
+	}
+
+	_, ok, err := rewriteImageReference("quay.io/prometheus/prometheus:v2", mirrors)
+	r.NoError(err)
+	r.False(ok)
+
+	_, _, err = rewriteImageReference("Invalid Image", mirrors)
+	r.Error(err)
+}
+
+func TestImageReference(t *testing.T) {
+	r := require.New(t)
+
+	path := filepath.Join(t.TempDir(), "mirrors")
+	r.NoError(os.WriteFile(path, []byte("docker.io=file-mirror.example.com\nghcr.io=ghcr-proxy.example.com\n"), 0o644))
+
+	t.Setenv("REGISTRY_MIRRORS_FILE", path)
+	t.Setenv("REGISTRY_MIRRORS", "docker.io=env-mirror.example.com")
+	t.Setenv("IMAGE_PREFIX", "prefix.example.com")
+
+	ref, err := imageReference("postgres:16.3")
+	r.NoError(err)
+	r.Equal("env-mirror.example.com/library/postgres:16.3", ref)
+
+	ref, err = imageReference("ghcr.io/teran/app:v1")
+	r.NoError(err)
+	r.Equal("ghcr-proxy.example.com/teran/app:v1", ref)
+
+	// No rule for the registry: IMAGE_PREFIX is applied
+	ref, err = imageReference("quay.io/prometheus/prometheus:v2")
+	r.NoError(err)
+	r.Equal("prefix.example.com/quay.io/prometheus/prometheus:v2", ref)
+
+	builtImages.Store("app:built", struct{}{})
+	t.Cleanup(func() { builtImages.Delete("app:built") })
+
+	ref, err = imageReference("app:built")
+	r.NoError(err)
+	r.Equal("app:built", ref)
+
+	t.Setenv("REGISTRY_MIRRORS", "invalid")
+	_, err = imageReference("postgres:16.3")
+	r.Error(err)
+
+	c, err := NewContainerWithClient(nil, "test", "postgres:16.3", nil, NewEnvironment(), NewPortBindings())
+	r.Error(err)
+	r.Nil(c)
+}
@@ -0,0 +1,150 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	dockerContainer "github.com/docker/docker/api/types/container"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/teran/go-docker-testsuite/images"
+)
+
+const (
+	netemCapability    = "NET_ADMIN"
+	netemSidecarSuffix = "-netem"
+	netemLogTailLines  = 20
+)
+
+// NetworkConditions describes the degradation of the container network
+// applied with tc netem to the egress traffic of every container interface
+// (except loopback), i.e. to the responses to the clients
+type NetworkConditions struct {
+	// Latency is the delay added to each packet
+	Latency time.Duration
+	// Jitter is the random variation of the latency
+	Jitter time.Duration
+	// Loss is the percentage (0-100) of packets dropped
+	Loss float64
+	// Bandwidth is the rate limit in bits per second, 0 means unlimited
+	Bandwidth uint64
+}
+
+func (nc NetworkConditions) netemArgs() ([]string, error) {
+	if nc.Latency < 0 || nc.Jitter < 0 {
+		return nil, errors.New("latency and jitter must not be negative")
+	}
+
+	if nc.Jitter > 0 && nc.Latency == 0 {
+		return nil, errors.New("jitter requires latency to be set")
+	}
+
+	if nc.Loss < 0 || nc.Loss > 100 {
+		return nil, errors.Errorf("loss must be in range 0-100, got %g", nc.Loss)
+	}
+
+	args := []string{}
+	if nc.Latency > 0 {
+		args = append(args, "delay", fmt.Sprintf("%dus", nc.Latency.Microseconds()))
+		if nc.Jitter > 0 {
+			args = append(args, fmt.Sprintf("%dus", nc.Jitter.Microseconds()))
+		}
+	}
+
+	if nc.Loss > 0 {
+		args = append(args, "loss", fmt.Sprintf("%g%%", nc.Loss))
+	}
+
+	if nc.Bandwidth > 0 {
+		args = append(args, "rate", fmt.Sprintf("%dbit", nc.Bandwidth))
+	}
+
+	return args, nil
+}
+
+// SetNetworkConditions applies latency, jitter, packet loss and bandwidth
+// limits to the container network replacing previously set conditions.
+// The tc command is run by the sidecar container joined to the container
+// network namespace with NET_ADMIN capability so the container image
+// doesn't need to have tc installed. Requires sch_netem kernel module on
+// the Docker host.
+func (c *container) SetNetworkConditions(ctx context.Context, nc NetworkConditions) error {
+	args, err := nc.netemArgs()
+	if err != nil {
+		return err
+	}
+
+	log.WithFields(log.Fields{
+		"name":       c.name,
+		"conditions": nc,
+	}).Debug("setting network conditions")
+
+	return c.runNetemSidecar(ctx, "tc qdisc replace dev \"$i\" root netem "+strings.Join(args, " "))
+}
+
+// ResetNetworkConditions removes the conditions set by SetNetworkConditions()
+func (c *container) ResetNetworkConditions(ctx context.Context) error {
+	log.WithFields(log.Fields{
+		"name": c.name,
+	}).Debug("resetting network conditions")
+
+	return c.runNetemSidecar(ctx, "tc qdisc del dev \"$i\" root 2>/dev/null || true")
+}
+
+// runNetemSidecar runs the command for each network interface of the
+// container from the sidecar container
+func (c *container) runNetemSidecar(ctx context.Context, cmd string) error {
+	if c.containerID == "" {
+		return ErrContainerIsNotRunning
+	}
+
+	script := "set -e; for i in $(ls /sys/class/net); do [ \"$i\" = lo ] && continue; " + cmd + "; done"
+
+	sc, err := NewContainerWithClient(
+		c.cli,
+		c.name+netemSidecarSuffix,
+		images.Netshoot,
+		[]string{"sh", "-c", script},
+		NewEnvironment(),
+		NewPortBindings(),
+		WithNetworkMode("container:"+c.containerID),
+		WithCapAdd(netemCapability),
+	)
+	if err != nil {
+		return err
+	}
+
+	// The sidecar is one-shot: in reuse mode Close() would leave it behind
+	sc.SetReuse(false)
+
+	if err := sc.Run(ctx); err != nil {
+		_ = sc.Close(ctx)
+		return errors.Wrap(err, "error running netem sidecar")
+	}
+	defer func() { _ = sc.Close(ctx) }()
+
+	sidecar := sc.(*container)
+
+	waitCh, errCh := c.cli.ContainerWait(ctx, sidecar.containerID, dockerContainer.WaitConditionNotRunning)
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		return errors.Wrap(err, "error waiting for netem sidecar")
+	case resp := <-waitCh:
+		if resp.StatusCode == 0 {
+			return nil
+		}
+
+		lines := []string{}
+		if entries, err := sidecar.tailLogs(ctx, netemLogTailLines); err == nil {
+			for _, e := range entries {
+				lines = append(lines, e.Line)
+			}
+		}
+		return errors.Errorf("netem sidecar exited with code %d: %s", resp.StatusCode, strings.Join(lines, "\n"))
+	}
+}
@@ -0,0 +1,84 @@
+package docker
+
+import (
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	log.SetLevel(log.TraceLevel)
+}
+
+func TestNetworkConditionsNetemArgs(t *testing.T) {
+	type testCase struct {
+		name     string
+		nc       NetworkConditions
+		expected []string
+		err      string
+	}
+
+	tcs := []testCase{
+		{
+			name:     "empty",
+			nc:       NetworkConditions{},
+			expected: []string{},
+		},
+		{
+			name: "latency with jitter",
+			nc: NetworkConditions{
+				Latency: 100 * time.Millisecond,
+				Jitter:  1500 * time.Microsecond,
+			},
+			expected: []string{"delay", "100000us", "1500us"},
+		},
+		{
+			name: "all of the conditions",
+			nc: NetworkConditions{
+				Latency:   time.Second,
+				Loss:      12.5,
+				Bandwidth: 1_000_000,
+			},
+			expected: []string{"delay", "1000000us", "loss", "12.5%", "rate", "1000000bit"},
+		},
+		{
+			name: "negative latency",
+			nc: NetworkConditions{
+				Latency: -time.Second,
+			},
+			err: "latency and jitter must not be negative",
+		},
+		{
+			name: "jitter without latency",
+			nc: NetworkConditions{
+				Jitter: time.Second,
+			},
+			err: "jitter requires latency to be set",
+		},
+		{
+			name: "loss out of range",
+			nc: NetworkConditions{
+				Loss: 101,
+			},
+			err: "loss must be in range 0-100, got 101",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			r := require.New(t)
+
+			args, err := tc.nc.netemArgs()
+			if tc.err != "" {
+				r.Error(err)
+				r.Equal(tc.err, err.Error())
+				return
+			}
+
+			r.NoError(err)
+			r.Equal(tc.expected, args)
+		})
+	}
+}
@@ -0,0 +1,264 @@
+package docker
+
+import (
+	"strconv"
+	"time"
+
+	dockerContainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/go-connections/nat"
+)
+
+// ContainerConfig is the full configuration the container is created with
+type ContainerConfig struct {
+	Config           *dockerContainer.Config
+	HostConfig       *dockerContainer.HostConfig
+	NetworkingConfig *network.NetworkingConfig
+	// RegistryAuth is the credentials the image is pulled with, resolved
+	// from the Docker config if not set
+	RegistryAuth *registry.AuthConfig
+	// PullPolicy defines when the image is pulled, set via PULL_POLICY
+	// environment variable if nil
+	PullPolicy *PullPolicy
+	// PullReporter receives the image pull progress, logged with debug
+	// level if nil
+	PullReporter PullReporter
+	// Platform is the os/arch[/variant] of the image, the Docker host
+	// platform if empty
+	Platform string
+	// ImageDigest is the digest the image is verified against after pull,
+	// looked up in the images lock file if empty
+	ImageDigest string
+}
+
+// ContainerOption modifies the container configuration before container creation.
+type ContainerOption func(*ContainerConfig)
+
+func newContainerConfig(pb *PortBindings, opts ...ContainerOption) *ContainerConfig {
+	pm := nat.PortMap{}
+	for k, v := range pb.portBindings {
+		for _, pb := range v {
+			p := nat.Port(k)
+			pm[p] = append(pm[p], nat.PortBinding{
+				HostIP:   pb.HostIP,
+				HostPort: pb.HostPort,
+			})
+		}
+	}
+
+	cc := &ContainerConfig{
+		Config: &dockerContainer.Config{
+			ExposedPorts: pb.portSet(),
+			Labels:       map[string]string{},
+		},
+		HostConfig: &dockerContainer.HostConfig{
+			NetworkMode:  dockerContainer.NetworkMode("default"),
+			PortBindings: pm,
+		},
+		NetworkingConfig: &network.NetworkingConfig{},
+	}
+	for _, opt := range opts {
+		opt(cc)
+	}
+	return cc
+}
+
+// WithPrivileged grants the container elevated privileges.
+func WithPrivileged() ContainerOption {
+	return func(cc *ContainerConfig) {
+		cc.HostConfig.Privileged = true
+	}
+}
+
+// WithTmpfs mounts tmpfs filesystems at the given paths.
+func WithTmpfs(m map[string]string) ContainerOption {
+	return func(cc *ContainerConfig) {
+		cc.HostConfig.Tmpfs = m
+	}
+}
+
+// WithBinds adds volume bind mounts (host:container[:mode]).
+func WithBinds(binds ...string) ContainerOption {
+	return func(cc *ContainerConfig) {
+		cc.HostConfig.Binds = binds
+	}
+}
+
+// WithNetworkMode sets the network mode of the container, i.e.
+// "container:<id>" to join the network namespace of another container.
+func WithNetworkMode(mode string) ContainerOption {
+	return func(cc *ContainerConfig) {
+		cc.HostConfig.NetworkMode = dockerContainer.NetworkMode(mode)
+	}
+}
+
+// WithCapAdd adds kernel capabilities to the container.
+func WithCapAdd(caps ...string) ContainerOption {
+	return func(cc *ContainerConfig) {
+		cc.HostConfig.CapAdd = append(cc.HostConfig.CapAdd, caps...)
+	}
+}
+
+// WithEntrypoint overrides the image entrypoint.
+func WithEntrypoint(entrypoint ...string) ContainerOption {
+	return func(cc *ContainerConfig) {
+		cc.Config.Entrypoint = entrypoint
+	}
+}
+
+// WithUser sets the user (user[:group]) the container processes run as.
+func WithUser(user string) ContainerOption {
+	return func(cc *ContainerConfig) {
+		cc.Config.User = user
+	}
+}
+
+// WithWorkingDir sets the working directory of the container processes.
+func WithWorkingDir(dir string) ContainerOption {
+	return func(cc *ContainerConfig) {
+		cc.Config.WorkingDir = dir
+	}
+}
+
+// WithHostname sets the hostname of the container.
+func WithHostname(hostname string) ContainerOption {
+	return func(cc *ContainerConfig) {
+		cc.Config.Hostname = hostname
+	}
+}
+
+// WithLabels adds labels to the container. Labels used by the testsuite
+// itself can't be overridden.
+func WithLabels(labels map[string]string) ContainerOption {
+	return func(cc *ContainerConfig) {
+		if cc.Config.Labels == nil {
+			cc.Config.Labels = map[string]string{}
+		}
+		for k, v := range labels {
+			cc.Config.Labels[k] = v
+		}
+	}
+}
+
+// WithStopSignal sets the signal (i.e. "SIGINT") sent to the container
+// to stop it.
+func WithStopSignal(signal string) ContainerOption {
+	return func(cc *ContainerConfig) {
+		cc.Config.StopSignal = signal
+	}
+}
+
+// WithStopTimeout sets the time the container is given to stop gracefully
+// before it's killed. It takes precedence over the context deadline in Close().
+func WithStopTimeout(timeout time.Duration) ContainerOption {
+	return func(cc *ContainerConfig) {
+		v := int(timeout / time.Second)
+		cc.Config.StopTimeout = &v
+	}
+}
+
+// WithExposedPorts exposes the container ports without mapping them
+// to the host, i.e. for the containers reachable within the Group only.
+func WithExposedPorts(proto Protocol, ports ...uint16) ContainerOption {
+	return func(cc *ContainerConfig) {
+		if cc.Config.ExposedPorts == nil {
+			cc.Config.ExposedPorts = nat.PortSet{}
+		}
+		for _, p := range ports {
+			cc.Config.ExposedPorts[nat.Port(strconv.FormatUint(uint64(p), 10)+"/"+proto.String())] = struct{}{}
+		}
+	}
+}
+
+// WithMemoryLimit sets the memory limit of the container in bytes. The
+// container is OOM killed once the limit is exceeded.
+func WithMemoryLimit(bytes int64) ContainerOption {
+	return func(cc *ContainerConfig) {
+		cc.HostConfig.Memory = bytes
+	}
+}
+
+// WithMemorySwapLimit sets the total memory plus swap limit of the container
+// in bytes, -1 allows unlimited swap. Equal to the memory limit disables swap.
+func WithMemorySwapLimit(bytes int64) ContainerOption {
+	return func(cc *ContainerConfig) {
+		cc.HostConfig.MemorySwap = bytes
+	}
+}
+
+// WithCPUs limits the amount of CPUs the container could use, i.e. 1.5
+func WithCPUs(cpus float64) ContainerOption {
+	return func(cc *ContainerConfig) {
+		cc.HostConfig.NanoCPUs = int64(cpus * 1e9)
+	}
+}
+
+// WithCPUSet pins the container to the specific CPUs, i.e. "0-2" or "0,3"
+func WithCPUSet(cpus string) ContainerOption {
+	return func(cc *ContainerConfig) {
+		cc.HostConfig.CpusetCpus = cpus
+	}
+}
+
+// WithPidsLimit limits the amount of processes in the container
+func WithPidsLimit(limit int64) ContainerOption {
+	return func(cc *ContainerConfig) {
+		cc.HostConfig.PidsLimit = &limit
+	}
+}
+
+// WithUlimit sets the ulimit (i.e. "nofile", "nproc") of the container
+// replacing the previously set one with the same name
+func WithUlimit(name string, soft, hard int64) ContainerOption {
+	return func(cc *ContainerConfig) {
+		ulimits := []*dockerContainer.Ulimit{}
+		for _, u := range cc.HostConfig.Ulimits {
+			if u.Name != name {
+				ulimits = append(ulimits, u)
+			}
+		}
+
+		cc.HostConfig.Ulimits = append(ulimits, &dockerContainer.Ulimit{
+			Name: name,
+			Soft: soft,
+			Hard: hard,
+		})
+	}
+}
+
+// WithCapDrop drops kernel capabilities of the container, "ALL" drops
+// all of them
+func WithCapDrop(caps ...string) ContainerOption {
+	return func(cc *ContainerConfig) {
+		cc.HostConfig.CapDrop = append(cc.HostConfig.CapDrop, caps...)
+	}
+}
+
+// WithSecurityOpt adds security options, i.e. "seccomp=unconfined",
+// "apparmor=unconfined" or "no-new-privileges"
+func WithSecurityOpt(opts ...string) ContainerOption {
+	return func(cc *ContainerConfig) {
+		cc.HostConfig.SecurityOpt = append(cc.HostConfig.SecurityOpt, opts...)
+	}
+}
+
+// WithReadOnlyRootfs mounts the container root filesystem as read only
+func WithReadOnlyRootfs() ContainerOption {
+	return func(cc *ContainerConfig) {
+		cc.HostConfig.ReadonlyRootfs = true
+	}
+}
+
+// WithTmpfsSize mounts tmpfs filesystem at the path limited to the size
+// in bytes. Unlike WithTmpfs it keeps the other tmpfs mounts.
+func WithTmpfsSize(path string, bytes int64) ContainerOption {
+	return func(cc *ContainerConfig) {
+		tmpfs := map[string]string{}
+		for k, v := range cc.HostConfig.Tmpfs {
+			tmpfs[k] = v
+		}
+		tmpfs[path] = "size=" + strconv.FormatInt(bytes, 10)
+		cc.HostConfig.Tmpfs = tmpfs
+	}
+}
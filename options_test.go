@@ -0,0 +1,135 @@
+package docker
+
+import (
+	"testing"
+	"time"
+
+	dockerContainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teran/go-docker-testsuite/internal/ptr"
+)
+
+func init() {
+	log.SetLevel(log.TraceLevel)
+}
+
+func TestNewContainerConfig(t *testing.T) {
+	r := require.New(t)
+
+	cc := newContainerConfig(NewPortBindings(),
+		WithEntrypoint("/bin/sh", "-c"),
+		WithUser("1000:1000"),
+		WithWorkingDir("/app"),
+		WithHostname("test-host"),
+		WithLabels(map[string]string{"a": "1"}),
+		WithLabels(map[string]string{"b": "2"}),
+		WithStopSignal("SIGINT"),
+		WithStopTimeout(10*time.Second),
+		WithExposedPorts(ProtoTCP, 8080, 9090),
+		WithPrivileged(),
+		WithTmpfs(map[string]string{"/run": "rw"}),
+		WithBinds("/lib/modules:/lib/modules:ro"),
+		WithCapAdd("NET_ADMIN"),
+		WithCapAdd("SYS_PTRACE"),
+		WithNetworkMode("host"),
+	)
+
+	r.Equal(&dockerContainer.Config{
+		Entrypoint: []string{"/bin/sh", "-c"},
+		User:       "1000:1000",
+		WorkingDir: "/app",
+		Hostname:   "test-host",
+		Labels: map[string]string{
+			"a": "1",
+			"b": "2",
+		},
+		StopSignal:  "SIGINT",
+		StopTimeout: ptr.Ptr(10),
+		ExposedPorts: nat.PortSet{
+			"8080/tcp": struct{}{},
+			"9090/tcp": struct{}{},
+		},
+	}, cc.Config)
+
+	r.Equal(&dockerContainer.HostConfig{
+		NetworkMode:  "host",
+		PortBindings: nat.PortMap{},
+		Privileged:   true,
+		Tmpfs:        map[string]string{"/run": "rw"},
+		Binds:        []string{"/lib/modules:/lib/modules:ro"},
+		CapAdd:       []string{"NET_ADMIN", "SYS_PTRACE"},
+	}, cc.HostConfig)
+}
+
+func TestContainerConfigLabels(t *testing.T) {
+	r := require.New(t)
+
+	c := newContainerForHostPort(t, 8080, "8080").(*container)
+	c.containerOpts = []ContainerOption{
+		WithLabels(map[string]string{
+			labelName:    "overridden",
+			labelSession: "overridden",
+			"custom":     "value",
+		}),
+	}
+
+	cc := c.containerConfig([]string{"A=1"})
+	r.Equal("image:test", cc.Config.Image)
+	r.Equal([]string{"A=1"}, cc.Config.Env)
+	r.Equal(nat.PortSet{"8080/tcp": struct{}{}}, cc.Config.ExposedPorts)
+	r.Equal("test", cc.Config.Labels[labelName])
+	r.Equal(SessionID(), cc.Config.Labels[labelSession])
+	r.Equal("value", cc.Config.Labels["custom"])
+
+	r.NotNil(c.stopOptions(t.Context()).Timeout)
+
+	c.config = newContainerConfig(c.ports, WithStopTimeout(5*time.Second))
+	r.Nil(c.stopOptions(t.Context()).Timeout)
+}
+
+func TestResourceAndSecurityOptions(t *testing.T) {
+	r := require.New(t)
+
+	tmpfs := map[string]string{"/run": ""}
+	cc := newContainerConfig(NewPortBindings(),
+		WithMemoryLimit(256*1024*1024),
+		WithMemorySwapLimit(512*1024*1024),
+		WithCPUs(1.5),
+		WithCPUSet("0-1"),
+		WithPidsLimit(100),
+		WithUlimit("nofile", 1024, 2048),
+		WithUlimit("nproc", 64, 64),
+		WithUlimit("nofile", 4096, 4096),
+		WithCapDrop("ALL"),
+		WithCapAdd("NET_BIND_SERVICE"),
+		WithSecurityOpt("no-new-privileges", "seccomp=unconfined"),
+		WithReadOnlyRootfs(),
+		WithTmpfs(tmpfs),
+		WithTmpfsSize("/tmp", 64*1024*1024),
+	)
+
+	hc := cc.HostConfig
+	r.Equal(int64(256*1024*1024), hc.Memory)
+	r.Equal(int64(512*1024*1024), hc.MemorySwap)
+	r.Equal(int64(1_500_000_000), hc.NanoCPUs)
+	r.Equal("0-1", hc.CpusetCpus)
+	r.Equal(ptr.Ptr[int64](100), hc.PidsLimit)
+	r.Equal([]*dockerContainer.Ulimit{
+		{Name: "nproc", Soft: 64, Hard: 64},
+		{Name: "nofile", Soft: 4096, Hard: 4096},
+	}, hc.Ulimits)
+	r.EqualValues([]string{"ALL"}, hc.CapDrop)
+	r.EqualValues([]string{"NET_BIND_SERVICE"}, hc.CapAdd)
+	r.Equal([]string{"no-new-privileges", "seccomp=unconfined"}, hc.SecurityOpt)
+	r.True(hc.ReadonlyRootfs)
+	r.Equal(map[string]string{
+		"/run": "",
+		"/tmp": "size=67108864",
+	}, hc.Tmpfs)
+
+	// Map passed to WithTmpfs is not modified
+	r.Equal(map[string]string{"/run": ""}, tmpfs)
+}
@@ -0,0 +1,102 @@
+package docker
+
+import (
+	"context"
+	"strings"
+
+	"github.com/docker/docker/api/types/image"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// WithPlatform sets the platform (os/arch[/variant], i.e. "linux/arm64" or
+// "linux/arm/v7") of the image the container is created from. The image
+// variant for the platform is pulled if the image present locally is built
+// for the other one. Platforms other than the Docker host one require
+// emulation (i.e. QEMU binfmt handlers) to be set up on the host.
+func WithPlatform(platform string) ContainerOption {
+	return func(cc *ContainerConfig) {
+		cc.Platform = platform
+	}
+}
+
+// parsePlatform parses os/arch[/variant] platform specification, empty
+// specification means the platform of the Docker host
+func parsePlatform(s string) (*ocispec.Platform, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(strings.ToLower(s), "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		return nil, errors.Errorf("invalid platform `%s`: os/arch[/variant] is expected", s)
+	}
+
+	for _, p := range parts {
+		if p == "" {
+			return nil, errors.Errorf("invalid platform `%s`: os/arch[/variant] is expected", s)
+		}
+	}
+
+	p := &ocispec.Platform{
+		OS:           parts[0],
+		Architecture: parts[1],
+	}
+	if len(parts) == 3 {
+		p.Variant = parts[2]
+	}
+	return p, nil
+}
+
+// formatPlatform returns os/arch[/variant] representation of the platform
+func formatPlatform(os, arch, variant string) string {
+	s := os + "/" + arch
+	if variant != "" {
+		s += "/" + variant
+	}
+	return s
+}
+
+// matchPlatform reports whether the local image matches the requested
+// platform. The variant is only compared if it's requested.
+func matchPlatform(p *ocispec.Platform, info image.InspectResponse) bool {
+	if p == nil {
+		return true
+	}
+
+	if p.OS != info.Os || p.Architecture != info.Architecture {
+		return false
+	}
+	return p.Variant == "" || p.Variant == info.Variant
+}
+
+// Platform returns the platform (os/arch[/variant]) of the image the
+// container is actually created from. It is only available after Run()
+// is called.
+func (c *container) Platform() string {
+	return c.platform
+}
+
+// resolvePlatform looks up the platform of the image the container is
+// created from
+func (c *container) resolvePlatform(ctx context.Context) error {
+	info, err := c.inspect(ctx)
+	if err != nil {
+		return errors.Wrap(err, "error inspecting container")
+	}
+
+	img, err := c.cli.ImageInspect(ctx, info.Image)
+	if err != nil {
+		return errors.Wrap(err, "error inspecting container image")
+	}
+
+	c.platform = formatPlatform(img.Os, img.Architecture, img.Variant)
+
+	log.WithFields(log.Fields{
+		"name":     c.name,
+		"platform": c.platform,
+	}).Trace("container platform resolved")
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types/image"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePlatform(t *testing.T) {
+	r := require.New(t)
+
+	p, err := parsePlatform("")
+	r.NoError(err)
+	r.Nil(p)
+
+	p, err = parsePlatform("linux/arm64")
+	r.NoError(err)
+	r.Equal(&ocispec.Platform{OS: "linux", Architecture: "arm64"}, p)
+
+	p, err = parsePlatform("Linux/ARM/v7")
+	r.NoError(err)
+	r.Equal(&ocispec.Platform{OS: "linux", Architecture: "arm", Variant: "v7"}, p)
+
+	for _, s := range []string{"linux", "linux/", "/amd64", "linux/arm/v7/extra"} {
+		_, err := parsePlatform(s)
+		r.Error(err, s)
+	}
+}
+
+func TestMatchPlatform(t *testing.T) {
+	r := require.New(t)
+
+	amd64 := image.InspectResponse{Os: "linux", Architecture: "amd64"}
+	armv7 := image.InspectResponse{Os: "linux", Architecture: "arm", Variant: "v7"}
+
+	r.True(matchPlatform(nil, amd64))
+	r.True(matchPlatform(&ocispec.Platform{OS: "linux", Architecture: "amd64"}, amd64))
+	r.False(matchPlatform(&ocispec.Platform{OS: "linux", Architecture: "arm64"}, amd64))
+	r.True(matchPlatform(&ocispec.Platform{OS: "linux", Architecture: "arm"}, armv7))
+	r.False(matchPlatform(&ocispec.Platform{OS: "linux", Architecture: "arm", Variant: "v6"}, armv7))
+
+	r.Equal("linux/arm/v7", formatPlatform(armv7.Os, armv7.Architecture, armv7.Variant))
+	r.Equal("linux/amd64", formatPlatform(amd64.Os, amd64.Architecture, amd64.Variant))
+}
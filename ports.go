@@ -23,25 +23,7 @@ type Binding struct {
 
 // NewHostConfig creates new HostConfig instance
 func NewHostConfig(pb *PortBindings, opts ...ContainerOption) (*dockerContainer.HostConfig, error) {
-	pm := nat.PortMap{}
-	for k, v := range pb.portBindings {
-		for _, pb := range v {
-			p := nat.Port(k)
-			pm[p] = append(pm[p], nat.PortBinding{
-				HostIP:   pb.HostIP,
-				HostPort: pb.HostPort,
-			})
-		}
-	}
-
-	hc := &dockerContainer.HostConfig{
-		NetworkMode:  dockerContainer.NetworkMode("default"),
-		PortBindings: pm,
-	}
-	for _, opt := range opts {
-		opt(hc)
-	}
-	return hc, nil
+	return newContainerConfig(pb, opts...).HostConfig, nil
 }
 
 // PortBindings is a full mapping of internal & external docker container ports
@@ -0,0 +1,513 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	pullMaxAttempts      = 5
+	pullInitialBackoff   = 1 * time.Second
+	pullMaxBackoff       = 30 * time.Second
+	pullProgressInterval = 5 * time.Second
+	// sharedPullTimeout limits the pull detached from the callers
+	sharedPullTimeout = 30 * time.Minute
+)
+
+// permanentPullErrors are the substrings of the pull errors which are not
+// fixed by retrying
+var permanentPullErrors = []string{
+	"not found",
+	"manifest unknown",
+	"unauthorized",
+	"denied",
+	"invalid reference",
+	"no matching manifest",
+}
+
+// jsonMessage is the single message of the progress stream returned by
+// the Docker daemon on image pull and build
+type jsonMessage struct {
+	Stream         string `json:"stream"`
+	Status         string `json:"status"`
+	ID             string `json:"id"`
+	ProgressDetail struct {
+		Current int64 `json:"current"`
+		Total   int64 `json:"total"`
+	} `json:"progressDetail"`
+	Error       string `json:"error"`
+	ErrorDetail *struct {
+		Message string `json:"message"`
+	} `json:"errorDetail"`
+}
+
+// err returns the error reported within the stream if any
+func (m *jsonMessage) err() error {
+	if m.ErrorDetail != nil && m.ErrorDetail.Message != "" {
+		return errors.New(m.ErrorDetail.Message)
+	}
+
+	if m.Error != "" {
+		return errors.New(m.Error)
+	}
+	return nil
+}
+
+// PullProgress is the progress event of the image pull
+type PullProgress struct {
+	// Image is the reference of the image being pulled
+	Image string
+	// Layer is the ID of the layer the event relates to, empty for
+	// the events of the whole image
+	Layer string
+	// Status is the status reported by Docker, i.e. "Downloading" or
+	// "Pull complete"
+	Status string
+	// Current is the amount of bytes processed
+	Current int64
+	// Total is the total amount of bytes or zero if it's unknown
+	Total int64
+}
+
+// String returns the human readable representation of the progress
+func (p PullProgress) String() string {
+	s := p.Status
+	if p.Layer != "" {
+		s = p.Layer + ": " + s
+	}
+
+	if p.Total > 0 {
+		s += fmt.Sprintf(" %d%% of %.1fMB", p.Current*100/p.Total, float64(p.Total)/1e6)
+	}
+	return s
+}
+
+// PullReporter receives the progress events of the image pulls
+type PullReporter interface {
+	Report(p PullProgress)
+}
+
+// PullReporterFunc allows to use plain function as PullReporter
+type PullReporterFunc func(p PullProgress)
+
+// Report implements PullReporter
+func (f PullReporterFunc) Report(p PullProgress) {
+	f(p)
+}
+
+// NewLogPullReporter logs the pull progress with the level. Progress of
+// the same layer with the same status is reported once in 5 seconds.
+func NewLogPullReporter(level log.Level) PullReporter {
+	th := newPullProgressThrottle()
+	return PullReporterFunc(func(p PullProgress) {
+		if !th.allow(p) {
+			return
+		}
+
+		log.WithFields(log.Fields{
+			"image": p.Image,
+		}).Log(level, p.String())
+	})
+}
+
+// NewTestingPullReporter passes the pull progress to t.Log the same way
+// as NewLogPullReporter does
+func NewTestingPullReporter(t testing.TB) PullReporter {
+	th := newPullProgressThrottle()
+	return PullReporterFunc(func(p PullProgress) {
+		if !th.allow(p) {
+			return
+		}
+
+		t.Logf("pulling `%s`: %s", p.Image, p)
+	})
+}
+
+// WithPullReporter sets the reporter receiving the image pull progress,
+// by default the progress is logged with debug level
+func WithPullReporter(r PullReporter) ContainerOption {
+	return func(cc *ContainerConfig) {
+		cc.PullReporter = r
+	}
+}
+
+type pullProgressThrottle struct {
+	mu   sync.Mutex
+	last map[string]PullProgress
+	at   map[string]time.Time
+}
+
+func newPullProgressThrottle() *pullProgressThrottle {
+	return &pullProgressThrottle{
+		last: map[string]PullProgress{},
+		at:   map[string]time.Time{},
+	}
+}
+
+// allow reports whether the event should be reported: the status
+// of the layer is changed or the interval since the last report is passed
+func (th *pullProgressThrottle) allow(p PullProgress) bool {
+	th.mu.Lock()
+	defer th.mu.Unlock()
+
+	k := p.Image + "\x00" + p.Layer
+	last, ok := th.last[k]
+	if ok && last.Status == p.Status && time.Since(th.at[k]) < pullProgressInterval {
+		return false
+	}
+
+	th.last[k] = p
+	th.at[k] = time.Now()
+	return true
+}
+
+// pullImageWithRetry pulls the image retrying transient failures with
+// exponential backoff
+func pullImageWithRetry(ctx context.Context, cli *client.Client, ref string, opts image.PullOptions, reporter PullReporter) error {
+	for attempt := 1; ; attempt++ {
+		err := pullImageOnce(ctx, cli, ref, opts, reporter)
+		if err == nil {
+			return nil
+		}
+
+		if attempt >= pullMaxAttempts || !isTransientPullError(ctx, err) {
+			return err
+		}
+
+		backoff := pullBackoff(attempt)
+		log.WithFields(log.Fields{
+			"image":   ref,
+			"attempt": attempt,
+			"backoff": backoff,
+			"error":   err,
+		}).Warn("image pull failed: retrying")
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(ctx.Err(), "error pulling image: %s", err)
+		case <-time.After(backoff):
+		}
+	}
+}
+
+func pullImageOnce(ctx context.Context, cli *client.Client, ref string, opts image.PullOptions, reporter PullReporter) error {
+	rc, err := cli.ImagePull(ctx, ref, opts)
+	if err != nil {
+		return errors.Wrap(err, "error pulling image")
+	}
+	defer func() { _ = rc.Close() }()
+
+	return errors.Wrap(readPullOutput(rc, ref, reporter), "error pulling image")
+}
+
+// readPullOutput reads the pull progress stream till the end passing
+// the progress to the reporter and returns the error reported within
+// the stream if any
+func readPullOutput(r io.Reader, ref string, reporter PullReporter) error {
+	dec := json.NewDecoder(r)
+	for {
+		msg := jsonMessage{}
+		if err := dec.Decode(&msg); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return errors.Wrap(err, "error reading pull progress")
+		}
+
+		if err := msg.err(); err != nil {
+			return err
+		}
+
+		if msg.Status == "" {
+			continue
+		}
+
+		reporter.Report(PullProgress{
+			Image:   ref,
+			Layer:   msg.ID,
+			Status:  msg.Status,
+			Current: msg.ProgressDetail.Current,
+			Total:   msg.ProgressDetail.Total,
+		})
+	}
+}
+
+// isTransientPullError reports whether the pull could succeed on retry
+func isTransientPullError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	if cerrdefs.IsNotFound(err) ||
+		cerrdefs.IsUnauthorized(err) ||
+		cerrdefs.IsPermissionDenied(err) ||
+		cerrdefs.IsInvalidArgument(err) {
+		return false
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, s := range permanentPullErrors {
+		if strings.Contains(msg, s) {
+			return false
+		}
+	}
+	return true
+}
+
+// pullBackoff returns the delay before the next attempt: the initial
+// backoff doubled after each attempt up to the maximum
+func pullBackoff(attempt int) time.Duration {
+	d := pullInitialBackoff
+	for i := 1; i < attempt && d < pullMaxBackoff; i++ {
+		d *= 2
+	}
+
+	if d > pullMaxBackoff {
+		return pullMaxBackoff
+	}
+	return d
+}
+
+// pullImage pulls the image according to the pull policy. Concurrent pulls
+// of the same image are de-duplicated within the process and across the
+// processes (i.e. test binaries of different packages run by `go test ./...`).
+// If the image reference is rewritten with the registry mirror or
+// IMAGE_PREFIX and the pull from the mirror fails, the image is pulled from
+// the original registry. The image pinned to the digest is verified against
+// it once pulled.
+func (c *container) pullImage(ctx context.Context, cc *ContainerConfig, platform *ocispec.Platform) error {
+	if isBuiltImage(c.image) {
+		return nil
+	}
+
+	policy, err := pullPolicy(cc)
+	if err != nil {
+		return err
+	}
+
+	pin, err := pinnedDigest(cc, c.originalImage)
+	if err != nil {
+		return err
+	}
+
+	if err := c.pullImageFromMirror(ctx, cc, policy, platform); err != nil {
+		return err
+	}
+
+	if pin == "" {
+		return nil
+	}
+	return c.verifyImageDigest(ctx, c.image, pin)
+}
+
+// pullImageFromMirror pulls the image by the rewritten reference falling back
+// to the original one
+func (c *container) pullImageFromMirror(ctx context.Context, cc *ContainerConfig, policy PullPolicy, platform *ocispec.Platform) error {
+	if c.originalImage != "" && c.image != c.originalImage {
+		_, mirrorCached := cachedImagePath(c.image)
+		if _, ok := cachedImagePath(c.originalImage); ok && !mirrorCached {
+			log.WithFields(log.Fields{
+				"mirror":   c.image,
+				"original": c.originalImage,
+			}).Trace("image is cached by the original reference: skipping the mirror")
+
+			c.image = c.originalImage
+			cc.Config.Image = c.image
+		}
+	}
+
+	err := c.pullImageShared(ctx, c.image, cc, policy, platform)
+	if err == nil || c.originalImage == "" || c.image == c.originalImage || ctx.Err() != nil {
+		return err
+	}
+
+	log.WithFields(log.Fields{
+		"mirror":   c.image,
+		"original": c.originalImage,
+		"error":    err,
+	}).Warn("error pulling image from the mirror: falling back to the original registry")
+
+	if fbErr := c.pullImageShared(ctx, c.originalImage, cc, policy, platform); fbErr != nil {
+		return errors.Wrapf(fbErr, "error pulling image from the original registry (mirror error: %s)", err)
+	}
+
+	c.image = c.originalImage
+	cc.Config.Image = c.image
+
+	return nil
+}
+
+// pullImageShared runs the pull shared with the concurrent Run() calls of
+// the same image. The pull runs on the context detached from the caller, so
+// the caller cancelled (i.e. the test timed out) doesn't fail the others
+// waiting for the same pull, each caller waits with its own context.
+func (c *container) pullImageShared(ctx context.Context, ref string, cc *ContainerConfig, policy PullPolicy, platform *ocispec.Platform) error {
+	shared := *cc
+	if cc.PullReporter != nil {
+		shared.PullReporter = callerPullReporter(ctx, cc.PullReporter)
+	}
+
+	ch := pullGroup.DoChan(ref+"\x00"+cc.Platform+"\x00"+policy.String(), func() (any, error) {
+		pullCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), sharedPullTimeout)
+		defer cancel()
+
+		return nil, c.pullImageLocked(pullCtx, ref, &shared, policy, platform)
+	})
+
+	select {
+	case <-ctx.Done():
+		return errors.Wrapf(ctx.Err(), "error pulling image `%s`", ref)
+	case res := <-ch:
+		if res.Shared {
+			log.WithFields(log.Fields{
+				"image": ref,
+			}).Trace("image pull is shared with concurrent Run()")
+		}
+		return res.Err
+	}
+}
+
+// callerPullReporter drops the progress once the context of the caller
+// started the shared pull is done, i.e. testing.TB reporter must not be
+// used after the test is completed
+func callerPullReporter(ctx context.Context, r PullReporter) PullReporter {
+	return PullReporterFunc(func(p PullProgress) {
+		if ctx.Err() != nil {
+			return
+		}
+		r.Report(p)
+	})
+}
+
+func (c *container) pullImageLocked(ctx context.Context, ref string, cc *ContainerConfig, policy PullPolicy, platform *ocispec.Platform) error {
+	pull, err := c.shouldPullImage(ctx, ref, policy, platform)
+	if err != nil || !pull {
+		return err
+	}
+
+	waitStartedAt := time.Now()
+	unlock, err := lockImagePull(ctx, ref)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// The image could be pulled by another process while waiting for the
+	// lock: the one tagged after the wait started is not pulled again
+	// regardless of the policy (always, default one for `:latest`)
+	present, pulledAt, err := c.inspectImage(ctx, ref, platform)
+	if err != nil {
+		return err
+	}
+
+	if pulledWhileWaiting(present, pulledAt, waitStartedAt) {
+		log.WithFields(log.Fields{
+			"image":     ref,
+			"pulled_at": pulledAt,
+		}).Debug("image is pulled by another process while waiting for the pull lock")
+		return nil
+	}
+
+	pull, err = c.shouldPullImage(ctx, ref, policy, platform)
+	if err != nil || !pull {
+		return err
+	}
+
+	auth, err := encodedRegistryAuth(ctx, ref, cc)
+	if err != nil {
+		return err
+	}
+
+	reporter := cc.PullReporter
+	if reporter == nil {
+		reporter = NewLogPullReporter(log.DebugLevel)
+	}
+
+	return pullImageWithRetry(ctx, c.cli, ref, image.PullOptions{
+		RegistryAuth: auth,
+		Platform:     cc.Platform,
+	}, reporter)
+}
+
+// pulledWhileWaiting reports whether the image is tagged (pulled) after the
+// pull lock wait is started
+func pulledWhileWaiting(present bool, pulledAt, waitStartedAt time.Time) bool {
+	return present && pulledAt.After(waitStartedAt)
+}
+
+// shouldPullImage evaluates the pull policy: the image built for the other
+// platform is treated as not present, missing image is loaded from
+// IMAGE_CACHE_DIR if it's there
+func (c *container) shouldPullImage(ctx context.Context, ref string, policy PullPolicy, platform *ocispec.Platform) (bool, error) {
+	present, pulledAt, err := c.inspectImage(ctx, ref, platform)
+	if err != nil {
+		return false, err
+	}
+
+	if !present {
+		loaded, err := loadCachedImage(ctx, c.cli, ref)
+		if err != nil {
+			return false, err
+		}
+
+		if loaded {
+			present, pulledAt, err = c.inspectImage(ctx, ref, platform)
+			if err != nil {
+				return false, err
+			}
+		}
+	}
+
+	pull, err := policy.shouldPull(ref, present, pulledAt)
+	if err != nil {
+		return false, err
+	}
+
+	log.WithFields(log.Fields{
+		"image":   ref,
+		"policy":  policy.String(),
+		"present": present,
+		"pull":    pull,
+	}).Trace("pull policy evaluated")
+
+	return pull, nil
+}
+
+// inspectImage reports whether the image for the platform is present locally
+// and the time it was pulled at
+func (c *container) inspectImage(ctx context.Context, ref string, platform *ocispec.Platform) (bool, time.Time, error) {
+	info, err := c.cli.ImageInspect(ctx, ref)
+	if err != nil {
+		if cerrdefs.IsNotFound(err) {
+			return false, time.Time{}, nil
+		}
+		return false, time.Time{}, errors.Wrapf(err, "error inspecting image `%s`", ref)
+	}
+
+	if !matchPlatform(platform, info) {
+		log.WithFields(log.Fields{
+			"image":    ref,
+			"platform": formatPlatform(info.Os, info.Architecture, info.Variant),
+		}).Trace("image present locally is built for the other platform")
+		return false, time.Time{}, nil
+	}
+
+	pulledAt := info.Metadata.LastTagTime
+	if pulledAt.IsZero() {
+		pulledAt, _ = time.Parse(time.RFC3339Nano, info.Created)
+	}
+	return true, pulledAt, nil
+}
@@ -0,0 +1,78 @@
+package docker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	pullLockDirectory    = "go-docker-testsuite/locks"
+	pullLockPollInterval = 100 * time.Millisecond
+)
+
+// pullGroup de-duplicates concurrent pulls of the same image within
+// the process
+var pullGroup singleflight.Group
+
+// lockImagePull acquires the file lock for the image in the user cache
+// directory so the image is pulled by a single process at a time. The lock
+// is skipped if the user cache directory is not available.
+func lockImagePull(ctx context.Context, ref string) (func(), error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Trace("user cache directory is not available: skipping pull lock")
+		return func() {}, nil
+	}
+
+	dir = filepath.Join(dir, filepath.FromSlash(pullLockDirectory))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, errors.Wrap(err, "error creating pull locks directory")
+	}
+
+	h := sha256.Sum256([]byte(ref))
+	path := filepath.Join(dir, hex.EncodeToString(h[:])[:16]+".lock")
+
+	fp, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, errors.Wrap(err, "error opening pull lock file")
+	}
+
+	for {
+		ok, err := tryLockFile(fp)
+		if err != nil {
+			_ = fp.Close()
+			return nil, errors.Wrap(err, "error locking pull lock file")
+		}
+
+		if ok {
+			break
+		}
+
+		log.WithFields(log.Fields{
+			"image": ref,
+			"lock":  path,
+		}).Trace("image is pulled by another process: waiting")
+
+		select {
+		case <-ctx.Done():
+			_ = fp.Close()
+			return nil, ctx.Err()
+		case <-time.After(pullLockPollInterval):
+		}
+	}
+
+	return func() {
+		_ = unlockFile(fp)
+		_ = fp.Close()
+	}, nil
+}
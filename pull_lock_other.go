@@ -0,0 +1,14 @@
+//go:build !unix
+
+package docker
+
+import "os"
+
+// File locks are not supported: pulls are de-duplicated within the process only
+func tryLockFile(fp *os.File) (bool, error) {
+	return true, nil
+}
+
+func unlockFile(fp *os.File) error {
+	return nil
+}
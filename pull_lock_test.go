@@ -0,0 +1,112 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockImagePull(t *testing.T) {
+	r := require.New(t)
+
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	unlock, err := lockImagePull(t.Context(), "postgres:16.3")
+	r.NoError(err)
+
+	// The other image is not blocked
+	unlockOther, err := lockImagePull(t.Context(), "redis:7")
+	r.NoError(err)
+	unlockOther()
+
+	ctx, cancel := context.WithTimeout(t.Context(), 300*time.Millisecond)
+	defer cancel()
+
+	_, err = lockImagePull(ctx, "postgres:16.3")
+	r.ErrorIs(err, context.DeadlineExceeded)
+
+	unlock()
+
+	unlock, err = lockImagePull(t.Context(), "postgres:16.3")
+	r.NoError(err)
+	unlock()
+}
+
+func TestPulledWhileWaiting(t *testing.T) {
+	r := require.New(t)
+
+	now := time.Now()
+	r.True(pulledWhileWaiting(true, now, now.Add(-time.Second)))
+	r.False(pulledWhileWaiting(true, now.Add(-time.Second), now))
+	r.False(pulledWhileWaiting(false, now, now.Add(-time.Second)))
+}
+
+func TestPullImageLockedSkipsPulledWhileWaiting(t *testing.T) {
+	r := require.New(t)
+
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+
+	// The fake daemon reports the image tagged at lastTagTime and counts
+	// the pulls
+	var lastTagTime atomic.Int64
+	lastTagTime.Store(time.Now().Add(-time.Hour).UnixNano())
+	var pulls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.URL.Path == "/_ping":
+			w.Header().Set("Api-Version", "1.47")
+		case strings.HasSuffix(req.URL.Path, "/images/create"):
+			pulls.Add(1)
+			_, _ = w.Write([]byte(`{"status":"Downloaded newer image"}`))
+		case strings.HasSuffix(req.URL.Path, "/json"):
+			_ = json.NewEncoder(w).Encode(image.InspectResponse{
+				ID:       "sha256:test",
+				Metadata: image.Metadata{LastTagTime: time.Unix(0, lastTagTime.Load())},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	cli, err := client.NewClientWithOpts(client.WithHost("tcp://"+srv.Listener.Addr().String()), client.WithAPIVersionNegotiation())
+	r.NoError(err)
+	defer func() { _ = cli.Close() }()
+
+	c := &container{name: "test", cli: cli}
+	cc := &ContainerConfig{}
+	ref := "example.com/app:latest"
+
+	// Uncontended lock: the image is pulled according to the policy
+	err = c.pullImageLocked(t.Context(), ref, cc, PullAlways, nil)
+	r.NoError(err)
+	r.Equal(int32(1), pulls.Load())
+
+	// The other process holds the lock and pulls the image meanwhile
+	unlock, err := lockImagePull(t.Context(), ref)
+	r.NoError(err)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- c.pullImageLocked(t.Context(), ref, cc, PullAlways, nil)
+	}()
+
+	time.Sleep(300 * time.Millisecond)
+	lastTagTime.Store(time.Now().UnixNano())
+	unlock()
+
+	r.NoError(<-errCh)
+	r.Equal(int32(1), pulls.Load())
+}
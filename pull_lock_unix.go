@@ -0,0 +1,21 @@
+//go:build unix
+
+package docker
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+func tryLockFile(fp *os.File) (bool, error) {
+	err := syscall.Flock(int(fp.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func unlockFile(fp *os.File) error {
+	return syscall.Flock(int(fp.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,143 @@
+package docker
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// pullPolicyEnvVar sets the pull policy for all of the containers
+	// without the policy set with WithPullPolicy()
+	pullPolicyEnvVar = "PULL_POLICY"
+
+	pullPolicyMaxAgePrefix = "max-age="
+)
+
+var ErrImageIsNotPresent = errors.New("image is not present locally and pull policy is never")
+
+type pullMode int
+
+const (
+	pullModeDefault pullMode = iota
+	pullModeAlways
+	pullModeIfNotPresent
+	pullModeNever
+	pullModeMaxAge
+)
+
+// PullPolicy defines when the image is pulled before the container creation.
+// The zero value pulls missing images and always re-pulls `:latest` ones.
+type PullPolicy struct {
+	mode   pullMode
+	maxAge time.Duration
+}
+
+var (
+	// PullAlways pulls the image on every Run()
+	PullAlways = PullPolicy{mode: pullModeAlways}
+	// PullIfNotPresent pulls the image only if it's not present locally
+	PullIfNotPresent = PullPolicy{mode: pullModeIfNotPresent}
+	// PullNever never pulls the image: Run() fails if it's not present
+	PullNever = PullPolicy{mode: pullModeNever}
+)
+
+// PullMaxAge pulls the image if it's not present locally or it was pulled
+// longer than maxAge ago
+func PullMaxAge(maxAge time.Duration) PullPolicy {
+	return PullPolicy{
+		mode:   pullModeMaxAge,
+		maxAge: maxAge,
+	}
+}
+
+// ParsePullPolicy parses the pull policy as it's set in PULL_POLICY
+// environment variable: "always", "if-not-present", "never" or
+// "max-age=<duration>" (i.e. "max-age=24h")
+func ParsePullPolicy(s string) (PullPolicy, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+
+	switch s {
+	case "always":
+		return PullAlways, nil
+	case "if-not-present":
+		return PullIfNotPresent, nil
+	case "never":
+		return PullNever, nil
+	}
+
+	if v, ok := strings.CutPrefix(s, pullPolicyMaxAgePrefix); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return PullPolicy{}, errors.Wrapf(err, "error parsing max age `%s`", v)
+		}
+
+		if d <= 0 {
+			return PullPolicy{}, errors.Errorf("max age must be positive, got `%s`", v)
+		}
+		return PullMaxAge(d), nil
+	}
+
+	return PullPolicy{}, errors.Errorf("unknown pull policy `%s`", s)
+}
+
+func (p PullPolicy) String() string {
+	switch p.mode {
+	case pullModeAlways:
+		return "always"
+	case pullModeIfNotPresent:
+		return "if-not-present"
+	case pullModeNever:
+		return "never"
+	case pullModeMaxAge:
+		return pullPolicyMaxAgePrefix + p.maxAge.String()
+	}
+	return "default"
+}
+
+// WithPullPolicy sets the pull policy of the container image overriding
+// the one set via PULL_POLICY environment variable
+func WithPullPolicy(p PullPolicy) ContainerOption {
+	return func(cc *ContainerConfig) {
+		cc.PullPolicy = &p
+	}
+}
+
+// pullPolicy returns the pull policy set with WithPullPolicy() or via
+// PULL_POLICY environment variable
+func pullPolicy(cc *ContainerConfig) (PullPolicy, error) {
+	if cc.PullPolicy != nil {
+		return *cc.PullPolicy, nil
+	}
+
+	v := os.Getenv(pullPolicyEnvVar)
+	if v == "" {
+		return PullPolicy{}, nil
+	}
+
+	p, err := ParsePullPolicy(v)
+	return p, errors.Wrapf(err, "error parsing %s", pullPolicyEnvVar)
+}
+
+// shouldPull reports whether the image has to be pulled: present is whether
+// the image is present locally and pulledAt is the time it was last
+// pulled (tagged) at
+func (p PullPolicy) shouldPull(image string, present bool, pulledAt time.Time) (bool, error) {
+	switch p.mode {
+	case pullModeAlways:
+		return true, nil
+	case pullModeIfNotPresent:
+		return !present, nil
+	case pullModeNever:
+		if !present {
+			return false, errors.Wrapf(ErrImageIsNotPresent, "`%s`", image)
+		}
+		return false, nil
+	case pullModeMaxAge:
+		return !present || time.Since(pulledAt) > p.maxAge, nil
+	}
+
+	return !present || strings.HasSuffix(image, ":latest"), nil
+}
@@ -0,0 +1,91 @@
+package docker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePullPolicy(t *testing.T) {
+	r := require.New(t)
+
+	for s, expected := range map[string]PullPolicy{
+		"always":         PullAlways,
+		" Never ":        PullNever,
+		"if-not-present": PullIfNotPresent,
+		"max-age=24h":    PullMaxAge(24 * time.Hour),
+	} {
+		p, err := ParsePullPolicy(s)
+		r.NoError(err)
+		r.Equal(expected, p)
+	}
+
+	for _, s := range []string{"", "sometimes", "max-age=", "max-age=tomorrow", "max-age=-1h"} {
+		_, err := ParsePullPolicy(s)
+		r.Error(err, s)
+	}
+
+	r.Equal("max-age=1h0m0s", PullMaxAge(time.Hour).String())
+	r.Equal("default", PullPolicy{}.String())
+}
+
+func TestPullPolicyShouldPull(t *testing.T) {
+	r := require.New(t)
+
+	now := time.Now()
+	old := now.Add(-48 * time.Hour)
+
+	type tc struct {
+		policy   PullPolicy
+		image    string
+		present  bool
+		pulledAt time.Time
+		expected bool
+	}
+
+	for _, c := range []tc{
+		{PullPolicy{}, "postgres:16.3", true, old, false},
+		{PullPolicy{}, "postgres:16.3", false, time.Time{}, true},
+		{PullPolicy{}, "postgres:latest", true, now, true},
+		{PullAlways, "postgres:16.3", true, now, true},
+		{PullIfNotPresent, "postgres:latest", true, old, false},
+		{PullIfNotPresent, "postgres:16.3", false, time.Time{}, true},
+		{PullNever, "postgres:latest", true, old, false},
+		{PullMaxAge(24 * time.Hour), "postgres:16.3", true, now, false},
+		{PullMaxAge(24 * time.Hour), "postgres:16.3", true, old, true},
+		{PullMaxAge(24 * time.Hour), "postgres:16.3", false, time.Time{}, true},
+	} {
+		pull, err := c.policy.shouldPull(c.image, c.present, c.pulledAt)
+		r.NoError(err)
+		r.Equal(c.expected, pull, "%s %s present=%t", c.policy, c.image, c.present)
+	}
+
+	_, err := PullNever.shouldPull("postgres:16.3", false, time.Time{})
+	r.ErrorIs(err, ErrImageIsNotPresent)
+}
+
+func TestPullPolicyPrecedence(t *testing.T) {
+	r := require.New(t)
+
+	t.Setenv("PULL_POLICY", "")
+
+	p, err := pullPolicy(newContainerConfig(NewPortBindings()))
+	r.NoError(err)
+	r.Equal(PullPolicy{}, p)
+
+	t.Setenv("PULL_POLICY", "never")
+
+	p, err = pullPolicy(newContainerConfig(NewPortBindings()))
+	r.NoError(err)
+	r.Equal(PullNever, p)
+
+	p, err = pullPolicy(newContainerConfig(NewPortBindings(), WithPullPolicy(PullAlways)))
+	r.NoError(err)
+	r.Equal(PullAlways, p)
+
+	t.Setenv("PULL_POLICY", "sometimes")
+
+	_, err = pullPolicy(newContainerConfig(NewPortBindings()))
+	r.Error(err)
+}
@@ -0,0 +1,103 @@
+package docker
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadPullOutput(t *testing.T) {
+	r := require.New(t)
+
+	events := []PullProgress{}
+	reporter := PullReporterFunc(func(p PullProgress) {
+		events = append(events, p)
+	})
+
+	err := readPullOutput(strings.NewReader(`{"status":"Pulling from library/postgres","id":"16.3"}
+{"status":"Pulling fs layer","progressDetail":{},"id":"a1b2"}
+{"status":"Downloading","progressDetail":{"current":512,"total":2048},"progress":"[==>   ]","id":"a1b2"}
+{"status":"Pull complete","progressDetail":{},"id":"a1b2"}
+{"status":"Digest: sha256:abc"}
+`), "postgres:16.3", reporter)
+	r.NoError(err)
+	r.Len(events, 5)
+	r.Equal(PullProgress{
+		Image:   "postgres:16.3",
+		Layer:   "a1b2",
+		Status:  "Downloading",
+		Current: 512,
+		Total:   2048,
+	}, events[2])
+	r.Equal("a1b2: Downloading 25% of 0.0MB", events[2].String())
+	r.Equal("Digest: sha256:abc", events[4].String())
+
+	err = readPullOutput(strings.NewReader(`{"status":"Pulling fs layer","progressDetail":{},"id":"a1b2"}
+{"errorDetail":{"message":"read tcp: connection reset by peer"},"error":"read tcp: connection reset by peer"}
+`), "postgres:16.3", reporter)
+	r.EqualError(err, "read tcp: connection reset by peer")
+}
+
+func TestIsTransientPullError(t *testing.T) {
+	r := require.New(t)
+
+	ctx := context.Background()
+
+	r.True(isTransientPullError(ctx, errors.New("read tcp: connection reset by peer")))
+	r.True(isTransientPullError(ctx, errors.New("toomanyrequests: rate limit exceeded")))
+	r.True(isTransientPullError(ctx, errors.Wrap(cerrdefs.ErrUnavailable, "error pulling image")))
+
+	r.False(isTransientPullError(ctx, errors.Wrap(cerrdefs.ErrNotFound, "error pulling image")))
+	r.False(isTransientPullError(ctx, errors.New("manifest unknown: manifest unknown")))
+	r.False(isTransientPullError(ctx, errors.New("pull access denied for app, repository does not exist")))
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	r.False(isTransientPullError(canceled, errors.New("read tcp: connection reset by peer")))
+}
+
+func TestPullBackoff(t *testing.T) {
+	r := require.New(t)
+
+	r.Equal(1*time.Second, pullBackoff(1))
+	r.Equal(2*time.Second, pullBackoff(2))
+	r.Equal(8*time.Second, pullBackoff(4))
+	r.Equal(30*time.Second, pullBackoff(6))
+	r.Equal(30*time.Second, pullBackoff(100))
+}
+
+func TestPullProgressThrottle(t *testing.T) {
+	r := require.New(t)
+
+	th := newPullProgressThrottle()
+
+	r.True(th.allow(PullProgress{Image: "app", Layer: "a", Status: "Downloading", Current: 1}))
+	r.False(th.allow(PullProgress{Image: "app", Layer: "a", Status: "Downloading", Current: 2}))
+	r.True(th.allow(PullProgress{Image: "app", Layer: "b", Status: "Downloading", Current: 1}))
+	r.True(th.allow(PullProgress{Image: "app", Layer: "a", Status: "Download complete"}))
+
+	th.at["app\x00a"] = time.Now().Add(-pullProgressInterval)
+	r.True(th.allow(PullProgress{Image: "app", Layer: "a", Status: "Download complete"}))
+}
+
+func TestCallerPullReporter(t *testing.T) {
+	r := require.New(t)
+
+	ctx, cancel := context.WithCancel(t.Context())
+
+	reported := []string{}
+	rep := callerPullReporter(ctx, PullReporterFunc(func(p PullProgress) {
+		reported = append(reported, p.Status)
+	}))
+
+	rep.Report(PullProgress{Status: "Downloading"})
+	cancel()
+	rep.Report(PullProgress{Status: "Pull complete"})
+
+	r.Equal([]string{"Downloading"}, reported)
+}
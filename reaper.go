@@ -0,0 +1,211 @@
+package docker
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	dockerContainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/teran/go-docker-testsuite/internal/random"
+)
+
+const (
+	labelSession = "go-docker-testsuite.session"
+	labelHost    = "go-docker-testsuite.host"
+	labelPID     = "go-docker-testsuite.pid"
+	// labelPIDStart is the start time of the process to tell it from
+	// the one the PID is recycled to
+	labelPIDStart = "go-docker-testsuite.pid-start"
+
+	// reaperDisabledEnvVar disables the orphaned resources sweep on startup
+	reaperDisabledEnvVar = "REAPER_DISABLED"
+	// reaperTTLEnvVar sets the age after which resources created on other
+	// hosts are considered orphaned
+	reaperTTLEnvVar  = "REAPER_TTL"
+	defaultReaperTTL = 1 * time.Hour
+)
+
+var (
+	sessionID = random.String(random.AlphaNumeric, 16)
+	reapOnce  sync.Once
+)
+
+// SessionID returns the identifier every container and network created by
+// the current process is labeled with
+func SessionID() string {
+	return sessionID
+}
+
+func sessionLabels() map[string]string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		log.WithError(err).Warn("error retrieving hostname")
+	}
+
+	labels := map[string]string{
+		labelSession: sessionID,
+		labelHost:    hostname,
+		labelPID:     strconv.Itoa(os.Getpid()),
+	}
+
+	if start, ok := processStartTime(os.Getpid()); ok {
+		labels[labelPIDStart] = start
+	}
+	return labels
+}
+
+// reapOrphansOnce runs Reap once per process on the first container or group
+// run. Errors are logged only since the sweep is not critical for the tests.
+func reapOrphansOnce(ctx context.Context, cli *client.Client) {
+	reapOnce.Do(func() {
+		if disabled, _ := strconv.ParseBool(os.Getenv(reaperDisabledEnvVar)); disabled {
+			log.Debug("orphaned resources sweep is disabled")
+			return
+		}
+
+		if err := Reap(ctx, cli); err != nil {
+			log.WithError(err).Warn("error removing orphaned resources")
+		}
+	})
+}
+
+// Reap removes containers and networks left by the sessions which are gone,
+// i.e. test binaries panicked or killed before Close() was called.
+// A session is considered gone when its process is not alive anymore (for
+// sessions started on the same host, the process start time is compared to
+// tell the recycled PID) or when its resources are older than REAPER_TTL
+// (1h by default, for sessions started on other hosts). Containers in reuse mode are only
+// removed once they're stopped since they're not reused anymore. The sweep
+// goes on after a failed removal, all of the errors are returned combined.
+func Reap(ctx context.Context, cli *client.Client) error {
+	ttl := defaultReaperTTL
+	if v := os.Getenv(reaperTTLEnvVar); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return errors.Wrapf(err, "error parsing %s value", reaperTTLEnvVar)
+		}
+		ttl = d
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return errors.Wrap(err, "error retrieving hostname")
+	}
+
+	containers, err := cli.ContainerList(ctx, dockerContainer.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", labelSession)),
+	})
+	if err != nil {
+		return errors.Wrap(err, "error listing containers")
+	}
+
+	var errs []error
+	for _, ct := range containers {
+		if !isReapable(ct, hostname, ttl) {
+			continue
+		}
+
+		log.WithFields(log.Fields{
+			"id":      ct.ID,
+			"name":    ct.Labels[labelName],
+			"session": ct.Labels[labelSession],
+		}).Info("removing orphaned container")
+
+		err := cli.ContainerRemove(ctx, ct.ID, dockerContainer.RemoveOptions{
+			RemoveVolumes: true,
+			Force:         true,
+		})
+		if err != nil {
+			log.WithFields(log.Fields{
+				"id": ct.ID,
+			}).WithError(err).Warn("error removing orphaned container")
+			errs = append(errs, errors.Wrapf(err, "error removing container `%s`", ct.ID))
+		}
+	}
+
+	networks, err := cli.NetworkList(ctx, network.ListOptions{
+		Filters: filters.NewArgs(filters.Arg("label", labelSession)),
+	})
+	if err != nil {
+		return errors.Wrap(err, "error listing networks")
+	}
+
+	for _, n := range networks {
+		if !isOrphaned(n.Labels, n.Created, hostname, ttl) {
+			continue
+		}
+
+		log.WithFields(log.Fields{
+			"id":      n.ID,
+			"name":    n.Name,
+			"session": n.Labels[labelSession],
+		}).Info("removing orphaned network")
+
+		if err := cli.NetworkRemove(ctx, n.ID); err != nil {
+			log.WithFields(log.Fields{
+				"id": n.ID,
+			}).WithError(err).Warn("error removing orphaned network")
+			errs = append(errs, errors.Wrapf(err, "error removing network `%s`", n.ID))
+		}
+	}
+
+	if len(errs) > 0 {
+		msgs := make([]string, 0, len(errs))
+		for _, e := range errs {
+			msgs = append(msgs, e.Error())
+		}
+		return errors.Errorf("orphaned resources sweep errors: [%s]", strings.Join(msgs, "; "))
+	}
+	return nil
+}
+
+// isReapable reports whether the container is to be removed: orphaned
+// containers and stopped reuse containers of other sessions (only running
+// containers are picked up for reuse)
+func isReapable(ct dockerContainer.Summary, hostname string, ttl time.Duration) bool {
+	if _, ok := ct.Labels[labelReuseHash]; ok {
+		if ct.Labels[labelSession] == sessionID {
+			return false
+		}
+		return ct.State == dockerContainer.StateExited || ct.State == dockerContainer.StateDead
+	}
+
+	return isOrphaned(ct.Labels, time.Unix(ct.Created, 0), hostname, ttl)
+}
+
+func isOrphaned(labels map[string]string, created time.Time, hostname string, ttl time.Duration) bool {
+	if labels[labelSession] == sessionID {
+		return false
+	}
+
+	if labels[labelHost] == hostname {
+		pid, err := strconv.Atoi(labels[labelPID])
+		if err == nil && pid > 0 {
+			if !isProcessAlive(pid) {
+				return true
+			}
+
+			// The PID could be recycled by another process, the start time
+			// tells it from the owner. The owner is assumed alive if the
+			// start time couldn't be verified.
+			if expected, ok := labels[labelPIDStart]; ok {
+				if start, ok := processStartTime(pid); ok && start != expected {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	return time.Since(created) > ttl
+}
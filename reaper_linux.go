@@ -0,0 +1,33 @@
+//go:build linux
+
+package docker
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// processStartTime returns the start time of the process in clock ticks
+// since boot (the 22nd field of /proc/<pid>/stat), it doesn't change for
+// the process lifetime and tells the recycled PID from the original process
+func processStartTime(pid int) (string, bool) {
+	data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return "", false
+	}
+
+	// The command name could contain spaces and parentheses so the fields
+	// are counted from the last closing one: the state is the 3rd field
+	s := string(data)
+	idx := strings.LastIndexByte(s, ')')
+	if idx < 0 {
+		return "", false
+	}
+
+	fields := strings.Fields(s[idx+1:])
+	if len(fields) < 20 {
+		return "", false
+	}
+	return fields[19], true
+}
@@ -0,0 +1,25 @@
+//go:build linux
+
+package docker
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessStartTime(t *testing.T) {
+	r := require.New(t)
+
+	start, ok := processStartTime(os.Getpid())
+	r.True(ok)
+	r.NotEmpty(start)
+
+	again, ok := processStartTime(os.Getpid())
+	r.True(ok)
+	r.Equal(start, again)
+
+	_, ok = processStartTime(2147483646)
+	r.False(ok)
+}
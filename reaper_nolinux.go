@@ -0,0 +1,9 @@
+//go:build !linux
+
+package docker
+
+// processStartTime is not supported, the process owning the resources is
+// considered alive as long as its PID is
+func processStartTime(pid int) (string, bool) {
+	return "", false
+}
@@ -0,0 +1,179 @@
+package docker
+
+import (
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	dockerContainer "github.com/docker/docker/api/types/container"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	log.SetLevel(log.TraceLevel)
+}
+
+func TestIsOrphaned(t *testing.T) {
+	type testCase struct {
+		name     string
+		labels   map[string]string
+		created  time.Time
+		expected bool
+	}
+
+	now := time.Now()
+	livePID := strconv.Itoa(os.Getpid())
+	deadPID := "2147483646"
+
+	tcs := []testCase{
+		{
+			name: "current session",
+			labels: map[string]string{
+				labelSession: SessionID(),
+				labelHost:    "localhost",
+				labelPID:     deadPID,
+			},
+			created:  now.Add(-24 * time.Hour),
+			expected: false,
+		},
+		{
+			name: "same host, process is alive",
+			labels: map[string]string{
+				labelSession: "other",
+				labelHost:    "localhost",
+				labelPID:     livePID,
+			},
+			created:  now.Add(-time.Minute),
+			expected: false,
+		},
+		{
+			name: "same host, process is alive, TTL expired",
+			labels: map[string]string{
+				labelSession: "other",
+				labelHost:    "localhost",
+				labelPID:     livePID,
+			},
+			created:  now.Add(-24 * time.Hour),
+			expected: false,
+		},
+		{
+			name: "same host, process is gone",
+			labels: map[string]string{
+				labelSession: "other",
+				labelHost:    "localhost",
+				labelPID:     deadPID,
+			},
+			created:  now,
+			expected: true,
+		},
+		{
+			name: "other host, within TTL",
+			labels: map[string]string{
+				labelSession: "other",
+				labelHost:    "remote",
+				labelPID:     deadPID,
+			},
+			created:  now.Add(-time.Minute),
+			expected: false,
+		},
+		{
+			name: "other host, TTL expired",
+			labels: map[string]string{
+				labelSession: "other",
+				labelHost:    "remote",
+				labelPID:     livePID,
+			},
+			created:  now.Add(-2 * time.Hour),
+			expected: true,
+		},
+		{
+			name: "same host, malformed pid, TTL expired",
+			labels: map[string]string{
+				labelSession: "other",
+				labelHost:    "localhost",
+				labelPID:     "blah",
+			},
+			created:  now.Add(-2 * time.Hour),
+			expected: true,
+		},
+	}
+
+	if start, ok := processStartTime(os.Getpid()); ok {
+		tcs = append(tcs, testCase{
+			name: "same host, process is alive, start time matches",
+			labels: map[string]string{
+				labelSession:  "other",
+				labelHost:     "localhost",
+				labelPID:      livePID,
+				labelPIDStart: start,
+			},
+			created:  now.Add(-24 * time.Hour),
+			expected: false,
+		}, testCase{
+			name: "same host, process is alive, start time differs (recycled PID)",
+			labels: map[string]string{
+				labelSession:  "other",
+				labelHost:     "localhost",
+				labelPID:      livePID,
+				labelPIDStart: start + "0",
+			},
+			created:  now,
+			expected: true,
+		})
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			r := require.New(t)
+			r.Equal(tc.expected, isOrphaned(tc.labels, tc.created, "localhost", time.Hour))
+		})
+	}
+}
+
+func TestIsReapable(t *testing.T) {
+	r := require.New(t)
+
+	now := time.Now()
+	reuse := func(session, state string) dockerContainer.Summary {
+		return dockerContainer.Summary{
+			Labels: map[string]string{
+				labelSession:   session,
+				labelHost:      "localhost",
+				labelPID:       strconv.Itoa(os.Getpid()),
+				labelReuseHash: "hash",
+			},
+			State:   state,
+			Created: now.Add(-24 * time.Hour).Unix(),
+		}
+	}
+
+	r.False(isReapable(reuse("other", dockerContainer.StateRunning), "localhost", time.Hour))
+	r.False(isReapable(reuse("other", dockerContainer.StatePaused), "localhost", time.Hour))
+	r.True(isReapable(reuse("other", dockerContainer.StateExited), "localhost", time.Hour))
+	r.True(isReapable(reuse("other", dockerContainer.StateDead), "localhost", time.Hour))
+	r.False(isReapable(reuse(SessionID(), dockerContainer.StateExited), "localhost", time.Hour))
+
+	r.True(isReapable(dockerContainer.Summary{
+		Labels: map[string]string{
+			labelSession: "other",
+			labelHost:    "remote",
+		},
+		State:   dockerContainer.StateRunning,
+		Created: now.Add(-2 * time.Hour).Unix(),
+	}, "localhost", time.Hour))
+}
+
+func TestSessionLabels(t *testing.T) {
+	r := require.New(t)
+
+	labels := sessionLabels()
+	r.Equal(SessionID(), labels[labelSession])
+	r.Equal(strconv.Itoa(os.Getpid()), labels[labelPID])
+	r.Len(SessionID(), 16)
+
+	if start, ok := processStartTime(os.Getpid()); ok {
+		r.Equal(start, labels[labelPIDStart])
+	}
+}
@@ -0,0 +1,13 @@
+//go:build unix
+
+package docker
+
+import (
+	"errors"
+	"syscall"
+)
+
+func isProcessAlive(pid int) bool {
+	err := syscall.Kill(pid, syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
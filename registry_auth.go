@@ -0,0 +1,248 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	dockerConfigEnvVar = "DOCKER_CONFIG"
+	dockerConfigFile   = "config.json"
+
+	dockerHubDomain    = "docker.io"
+	dockerHubServerURL = "https://index.docker.io/v1/"
+
+	credentialHelperPrefix      = "docker-credential-"
+	credentialHelperNotFound    = "credentials not found"
+	credentialHelperTokenMarker = "<token>"
+)
+
+// WithRegistryAuth sets the credentials the image is pulled with instead of
+// the ones resolved from the Docker config. The credentials are used for the
+// registry the image is actually pulled from, i.e. the registry mirror.
+func WithRegistryAuth(auth registry.AuthConfig) ContainerOption {
+	return func(cc *ContainerConfig) {
+		cc.RegistryAuth = &auth
+	}
+}
+
+type dockerConfig struct {
+	Auths       map[string]dockerConfigAuth `json:"auths"`
+	CredsStore  string                      `json:"credsStore"`
+	CredHelpers map[string]string           `json:"credHelpers"`
+}
+
+type dockerConfigAuth struct {
+	Auth          string `json:"auth"`
+	Username      string `json:"username"`
+	Password      string `json:"password"`
+	IdentityToken string `json:"identitytoken"`
+	RegistryToken string `json:"registrytoken"`
+}
+
+// loadDockerConfig reads config.json from $DOCKER_CONFIG or ~/.docker,
+// missing config is treated as empty one
+func loadDockerConfig() (*dockerConfig, error) {
+	dir := os.Getenv(dockerConfigEnvVar)
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return &dockerConfig{}, nil
+		}
+		dir = filepath.Join(home, ".docker")
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, dockerConfigFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &dockerConfig{}, nil
+		}
+		return nil, errors.Wrap(err, "error reading Docker config")
+	}
+
+	dc := &dockerConfig{}
+	if err := json.Unmarshal(data, dc); err != nil {
+		return nil, errors.Wrap(err, "error parsing Docker config")
+	}
+	return dc, nil
+}
+
+// registryDomain returns the domain of the registry the image is pulled from
+func registryDomain(image string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", errors.Wrapf(err, "error parsing image reference `%s`", image)
+	}
+	return reference.Domain(named), nil
+}
+
+// resolveRegistryAuth looks up the credentials for the registry of the image
+// in the Docker config: credential helper configured for the registry in
+// credHelpers, credsStore and auths in order. It returns nil if there're no
+// credentials for the registry.
+func resolveRegistryAuth(ctx context.Context, image string) (*registry.AuthConfig, error) {
+	domain, err := registryDomain(image)
+	if err != nil {
+		return nil, err
+	}
+
+	dc, err := loadDockerConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return dc.authFor(ctx, domain)
+}
+
+func (dc *dockerConfig) authFor(ctx context.Context, domain string) (*registry.AuthConfig, error) {
+	serverURL := domain
+	if domain == dockerHubDomain {
+		serverURL = dockerHubServerURL
+	}
+
+	for _, helper := range []string{dc.CredHelpers[domain], dc.CredsStore} {
+		if helper == "" {
+			continue
+		}
+
+		auth, err := credentialHelperAuth(ctx, helper, serverURL)
+		if err != nil {
+			return nil, err
+		}
+
+		if auth != nil {
+			return auth, nil
+		}
+	}
+
+	for k, v := range dc.Auths {
+		if authKeyDomain(k) != authKeyDomain(serverURL) {
+			continue
+		}
+
+		auth := &registry.AuthConfig{
+			Username:      v.Username,
+			Password:      v.Password,
+			ServerAddress: serverURL,
+			IdentityToken: v.IdentityToken,
+			RegistryToken: v.RegistryToken,
+		}
+
+		if v.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(v.Auth)
+			if err != nil {
+				return nil, errors.Wrapf(err, "error decoding auth for `%s`", k)
+			}
+
+			username, password, ok := strings.Cut(string(decoded), ":")
+			if !ok {
+				return nil, errors.Errorf("invalid auth for `%s`: username and password are expected", k)
+			}
+			auth.Username, auth.Password = username, password
+		}
+
+		log.WithFields(log.Fields{
+			"registry": domain,
+			"username": auth.Username,
+		}).Trace("registry credentials found in Docker config")
+
+		return auth, nil
+	}
+
+	return nil, nil
+}
+
+// authKeyDomain normalizes the auths key which could be the domain or the URL
+func authKeyDomain(key string) string {
+	key = strings.TrimPrefix(key, "https://")
+	key = strings.TrimPrefix(key, "http://")
+	domain, _, _ := strings.Cut(key, "/")
+	return domain
+}
+
+// credentialHelperAuth requests the credentials from the docker-credential-*
+// helper binary. It returns nil if the helper has no credentials for
+// the server.
+func credentialHelperAuth(ctx context.Context, helper, serverURL string) (*registry.AuthConfig, error) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, credentialHelperPrefix+helper, "get")
+	cmd.Stdin = strings.NewReader(serverURL)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	log.WithFields(log.Fields{
+		"helper":   helper,
+		"registry": serverURL,
+	}).Trace("requesting registry credentials from credential helper")
+
+	if err := cmd.Run(); err != nil {
+		// Helpers report the error to stdout, stderr is checked too since
+		// some of them print it there
+		out := strings.TrimSpace(stdout.String() + "\n" + stderr.String())
+		if strings.Contains(out, credentialHelperNotFound) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "error running credential helper `%s`: %s", helper, out)
+	}
+
+	if stderr.Len() > 0 {
+		log.WithFields(log.Fields{
+			"helper": helper,
+			"stderr": strings.TrimSpace(stderr.String()),
+		}).Debug("credential helper printed to stderr")
+	}
+
+	resp := struct {
+		ServerURL string `json:"ServerURL"`
+		Username  string `json:"Username"`
+		Secret    string `json:"Secret"`
+	}{}
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return nil, errors.Wrapf(err, "error parsing credential helper `%s` output", helper)
+	}
+
+	if resp.Username == credentialHelperTokenMarker {
+		return &registry.AuthConfig{
+			IdentityToken: resp.Secret,
+			ServerAddress: serverURL,
+		}, nil
+	}
+
+	return &registry.AuthConfig{
+		Username:      resp.Username,
+		Password:      resp.Secret,
+		ServerAddress: serverURL,
+	}, nil
+}
+
+// encodedRegistryAuth returns the RegistryAuth value to pull the image with:
+// the credentials set with WithRegistryAuth() or resolved from the Docker
+// config for the registry of the (rewritten) image reference
+func encodedRegistryAuth(ctx context.Context, ref string, cc *ContainerConfig) (string, error) {
+	auth := cc.RegistryAuth
+	if auth == nil {
+		var err error
+		auth, err = resolveRegistryAuth(ctx, ref)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if auth == nil {
+		return "", nil
+	}
+
+	encoded, err := registry.EncodeAuthConfig(*auth)
+	return encoded, errors.Wrap(err, "error encoding registry credentials")
+}
@@ -0,0 +1,112 @@
+package docker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/api/types/registry"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryDomain(t *testing.T) {
+	r := require.New(t)
+
+	for image, domain := range map[string]string{
+		"postgres:16.3":                       "docker.io",
+		"index.docker.io/library/redis:7":     "docker.io",
+		"ghcr.io/teran/echo-grpc-server:v1":   "ghcr.io",
+		"registry.example.com:5000/app:1.0":   "registry.example.com:5000",
+		"mirror.example.com/library/postgres": "mirror.example.com",
+	} {
+		d, err := registryDomain(image)
+		r.NoError(err)
+		r.Equal(domain, d, image)
+	}
+
+	_, err := registryDomain("Invalid Image")
+	r.Error(err)
+}
+
+func TestResolveRegistryAuth(t *testing.T) {
+	r := require.New(t)
+
+	dir := t.TempDir()
+	t.Setenv("DOCKER_CONFIG", dir)
+	t.Setenv("PATH", dir)
+
+	// No config means no credentials
+	auth, err := resolveRegistryAuth(t.Context(), "ghcr.io/teran/app:v1")
+	r.NoError(err)
+	r.Nil(auth)
+
+	err = os.WriteFile(filepath.Join(dir, "docker-credential-fake"), []byte(`#!/bin/sh
+read server
+case "$server" in
+  helper.example.com) echo "warning: keychain is unlocked" >&2; echo '{"ServerURL":"helper.example.com","Username":"helper-user","Secret":"helper-secret"}' ;;
+  broken.example.com) echo "keychain is not available" >&2; exit 1 ;;
+  token.example.com) echo '{"ServerURL":"token.example.com","Username":"<token>","Secret":"identity"}' ;;
+  *) echo "credentials not found in native keychain"; exit 1 ;;
+esac
+`), 0o755)
+	r.NoError(err)
+
+	err = os.WriteFile(filepath.Join(dir, "config.json"), []byte(`{
+  "auths": {
+    "https://index.docker.io/v1/": {"auth": "aHViLXVzZXI6aHViLXBhc3M="},
+    "ghcr.io": {"auth": "Z2hjci11c2VyOmdoY3I6cGFzcw=="},
+    "https://mirror.example.com": {"username": "mirror-user", "password": "mirror-pass"}
+  },
+  "credsStore": "fake",
+  "credHelpers": {
+    "helper.example.com": "fake"
+  }
+}`), 0o644)
+	r.NoError(err)
+
+	for image, expected := range map[string]*registry.AuthConfig{
+		"postgres:16.3":                 {Username: "hub-user", Password: "hub-pass", ServerAddress: "https://index.docker.io/v1/"},
+		"ghcr.io/teran/app:v1":          {Username: "ghcr-user", Password: "ghcr:pass", ServerAddress: "ghcr.io"},
+		"mirror.example.com/postgres:1": {Username: "mirror-user", Password: "mirror-pass", ServerAddress: "mirror.example.com"},
+		"helper.example.com/app:v1":     {Username: "helper-user", Password: "helper-secret", ServerAddress: "helper.example.com"},
+		"token.example.com/app:v1":      {IdentityToken: "identity", ServerAddress: "token.example.com"},
+		"unknown.example.com/app:v1":    nil,
+	} {
+		auth, err := resolveRegistryAuth(t.Context(), image)
+		r.NoError(err)
+		r.Equal(expected, auth, image)
+	}
+
+	_, err = resolveRegistryAuth(t.Context(), "broken.example.com/app:v1")
+	r.Error(err)
+	r.Contains(err.Error(), "keychain is not available")
+}
+
+func TestEncodedRegistryAuth(t *testing.T) {
+	r := require.New(t)
+
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+	t.Setenv("IMAGE_PREFIX", "mirror.example.com")
+
+	c, err := NewContainerWithClient(nil, "test", "postgres:16.3", nil, NewEnvironment(), NewPortBindings(),
+		WithRegistryAuth(registry.AuthConfig{Username: "user", Password: "pass"}),
+	)
+	r.NoError(err)
+
+	cont := c.(*container)
+	encoded, err := encodedRegistryAuth(t.Context(), cont.image, cont.containerConfig(nil))
+	r.NoError(err)
+
+	auth, err := registry.DecodeAuthConfig(encoded)
+	r.NoError(err)
+	r.Equal("user", auth.Username)
+	r.Equal("pass", auth.Password)
+
+	c, err = NewContainerWithClient(nil, "test", "postgres:16.3", nil, NewEnvironment(), NewPortBindings())
+	r.NoError(err)
+
+	cont = c.(*container)
+	encoded, err = encodedRegistryAuth(t.Context(), cont.image, cont.containerConfig(nil))
+	r.NoError(err)
+	r.Empty(encoded)
+}
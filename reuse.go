@@ -0,0 +1,177 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	dockerContainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	labelName      = "go-docker-testsuite.name"
+	labelReuseHash = "go-docker-testsuite.reuse-hash"
+
+	// reuseEnvVar enables containers reuse for all of the containers
+	reuseEnvVar = "CONTAINER_REUSE"
+)
+
+// SetReuse enables or disables reuse mode for the container: Run() looks up
+// for already running container with the same configuration and uses it
+// instead of creating new one, Close() leaves the container running so it
+// could be picked up by the next test run. Reuse mode could be enabled for
+// all containers via CONTAINER_REUSE=true environment variable.
+//
+// NOTE: the configuration hash includes evaluated environment so containers
+// which environment depends on allocated host ports are never reused. Host
+// config, health check and the content copied before Run() are part of
+// the hash too.
+func (c *container) SetReuse(enabled bool) {
+	c.reuse = &enabled
+}
+
+func (c *container) reuseEnabled() bool {
+	if c.reuse != nil {
+		return *c.reuse
+	}
+
+	v, err := strconv.ParseBool(os.Getenv(reuseEnvVar))
+	if err != nil {
+		return false
+	}
+	return v
+}
+
+// configHash returns the hash of the container configuration used to
+// match the container for reuse: the container config, the host config
+// (except the allocated host ports), the health check and the archives
+// copied into the container before Run()
+func (c *container) configHash(cc *ContainerConfig) string {
+	env := append([]string{}, cc.Config.Env...)
+	sort.Strings(env)
+
+	ports := []string{}
+	for p := range cc.Config.ExposedPorts {
+		ports = append(ports, string(p))
+	}
+	sort.Strings(ports)
+
+	h := sha256.New()
+	for _, part := range [][]string{
+		{c.name},
+		{cc.Config.Image},
+		cc.Config.Cmd,
+		env,
+		ports,
+		cc.Config.Entrypoint,
+		{cc.Config.User, cc.Config.WorkingDir, cc.Config.Hostname},
+		{cc.Platform},
+		{canonicalJSON(hostConfigForHash(cc.HostConfig))},
+		{canonicalJSON(cc.Config.Healthcheck)},
+		archiveHashes(c.pendingCopies),
+	} {
+		_, _ = h.Write([]byte(strings.Join(part, "\x00")))
+		_, _ = h.Write([]byte{0xff})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// hostConfigForHash returns the copy of the host config without the host
+// ports which are allocated on every run
+func hostConfigForHash(hc *dockerContainer.HostConfig) *dockerContainer.HostConfig {
+	if hc == nil {
+		return nil
+	}
+
+	cp := *hc
+	cp.PortBindings = nil
+	return &cp
+}
+
+// canonicalJSON returns the JSON representation of the value, map keys are
+// sorted by encoding/json so the representation is stable. Docker API types
+// are always encodable so the error is ignored.
+func canonicalJSON(v any) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// archiveHashes returns the hashes of the archives content: names, modes,
+// link targets and file contents, modification times are not taken into
+// account
+func archiveHashes(archives [][]byte) []string {
+	out := make([]string, 0, len(archives))
+	for _, a := range archives {
+		h := sha256.New()
+		tr := tar.NewReader(bytes.NewReader(a))
+		for {
+			hdr, err := tr.Next()
+			if err != nil {
+				// Archives are built by CopyTo()/CopyPathTo() so the only
+				// expected error is io.EOF
+				break
+			}
+
+			_, _ = fmt.Fprintf(h, "%s\x00%c\x00%o\x00%s\x00", hdr.Name, hdr.Typeflag, hdr.Mode, hdr.Linkname)
+			_, _ = io.Copy(h, tr)
+			_, _ = h.Write([]byte{0xff})
+		}
+		out = append(out, hex.EncodeToString(h.Sum(nil)))
+	}
+	return out
+}
+
+// lookupReusable looks up for running container created with the same
+// configuration hash and takes it over if any
+func (c *container) lookupReusable(ctx context.Context, hash string) (bool, error) {
+	containers, err := c.cli.ContainerList(ctx, dockerContainer.ListOptions{
+		Filters: filters.NewArgs(
+			filters.Arg("label", labelReuseHash+"="+hash),
+			filters.Arg("status", "running"),
+		),
+	})
+	if err != nil {
+		return false, errors.Wrap(err, "error listing containers for reuse")
+	}
+
+	if len(containers) == 0 {
+		return false, nil
+	}
+
+	found := containers[0]
+
+	log.WithFields(log.Fields{
+		"name": c.name,
+		"id":   found.ID,
+		"hash": hash,
+	}).Debug("reusing running container")
+
+	for _, p := range found.Ports {
+		if p.PublicPort == 0 {
+			continue
+		}
+
+		k := strconv.FormatUint(uint64(p.PrivatePort), 10) + "/" + p.Type
+		bs, ok := c.ports.portBindings[k]
+		if !ok || len(bs) == 0 {
+			continue
+		}
+		bs[0].HostPort = strconv.FormatUint(uint64(p.PublicPort), 10)
+	}
+
+	c.containerID = found.ID
+
+	return true, nil
+}
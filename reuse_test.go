@@ -0,0 +1,87 @@
+package docker
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigHash(t *testing.T) {
+	r := require.New(t)
+
+	t.Setenv("DOCKER_HOST", "tcp://1.1.1.1:9874")
+
+	var count uint16 = 12000
+	newContainer := func(opts []ContainerOption, cmd ...string) *container {
+		c, err := NewContainerWithClient(nil, "test", "image:test", cmd, NewEnvironment(),
+			NewPortBindingsWithPortAllocator(func(proto Protocol, port uint16) (string, uint16, []string, error) {
+				count++
+				return strconv.FormatUint(uint64(port), 10) + "/" + proto.String(), count, []string{}, nil
+			}).
+				PortDNAT(ProtoTCP, 5432).
+				PortDNAT(ProtoUDP, 53),
+			opts...,
+		)
+		r.NoError(err)
+		return c.(*container)
+	}
+
+	hash := func(c *container, env ...string) string {
+		return c.configHash(c.containerConfig(env))
+	}
+
+	// Allocated host ports, environment order and labels do not affect the hash
+	h1 := hash(newContainer(nil, "serve"), "A=1", "B=2")
+	h2 := hash(newContainer([]ContainerOption{WithLabels(map[string]string{"k": "v"})}, "serve"), "B=2", "A=1")
+	r.Equal(h1, h2)
+
+	r.NotEqual(h1, hash(newContainer(nil, "serve", "--debug"), "A=1", "B=2"))
+	r.NotEqual(h1, hash(newContainer(nil, "serve"), "A=1", "B=3"))
+	r.NotEqual(h1, hash(newContainer(nil, "ser", "ve"), "A=1", "B=2"))
+	r.NotEqual(h1, hash(newContainer([]ContainerOption{WithUser("nobody")}, "serve"), "A=1", "B=2"))
+	r.NotEqual(h1, hash(newContainer([]ContainerOption{WithEntrypoint("/bin/sh")}, "serve"), "A=1", "B=2"))
+	r.NotEqual(h1, hash(newContainer([]ContainerOption{WithPlatform("linux/arm64")}, "serve"), "A=1", "B=2"))
+	r.NotEqual(h1, hash(newContainer([]ContainerOption{WithBinds("/tmp:/data")}, "serve"), "A=1", "B=2"))
+	r.NotEqual(h1, hash(newContainer([]ContainerOption{WithPrivileged()}, "serve"), "A=1", "B=2"))
+	r.NotEqual(h1, hash(newContainer([]ContainerOption{WithMemoryLimit(64 << 20)}, "serve"), "A=1", "B=2"))
+	r.NotEqual(h1, hash(newContainer([]ContainerOption{WithCapDrop("ALL")}, "serve"), "A=1", "B=2"))
+
+	withHealthcheck := newContainer(nil, "serve")
+	withHealthcheck.SetHealthcheck(&Healthcheck{Test: []string{"CMD", "true"}})
+	r.NotEqual(h1, hash(withHealthcheck, "A=1", "B=2"))
+
+	withCopy := newContainer(nil, "serve")
+	r.NoError(withCopy.CopyTo(t.Context(), strings.NewReader("data"), "/data/file", 0o644))
+	h3 := hash(withCopy, "A=1", "B=2")
+	r.NotEqual(h1, h3)
+
+	withOtherCopy := newContainer(nil, "serve")
+	r.NoError(withOtherCopy.CopyTo(t.Context(), strings.NewReader("other data"), "/data/file", 0o644))
+	r.NotEqual(h3, hash(withOtherCopy, "A=1", "B=2"))
+
+	// Archive modification times do not affect the hash
+	withSameCopy := newContainer(nil, "serve")
+	r.NoError(withSameCopy.CopyTo(t.Context(), strings.NewReader("data"), "/data/file", 0o644))
+	r.Equal(h3, hash(withSameCopy, "A=1", "B=2"))
+}
+
+func TestReuseEnabled(t *testing.T) {
+	r := require.New(t)
+
+	c := &container{}
+
+	t.Setenv("CONTAINER_REUSE", "")
+	r.False(c.reuseEnabled())
+
+	t.Setenv("CONTAINER_REUSE", "true")
+	r.True(c.reuseEnabled())
+
+	c.SetReuse(false)
+	r.False(c.reuseEnabled())
+
+	t.Setenv("CONTAINER_REUSE", "false")
+	c.SetReuse(true)
+	r.True(c.reuseEnabled())
+}
@@ -0,0 +1,164 @@
+package docker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultLogTailLines = 50
+)
+
+// Closer is anything started by the testsuite: Container, Group or
+// any of the applications
+type Closer interface {
+	Close(ctx context.Context) error
+}
+
+// TestOption customizes the behavior of the testing helpers
+type TestOption func(*testOptions)
+
+type testOptions struct {
+	logTailLines int
+}
+
+// WithLogTailLines sets the amount of the last container output lines
+// dumped when the test is failed. Zero disables the dump.
+func WithLogTailLines(n int) TestOption {
+	return func(o *testOptions) {
+		o.logTailLines = n
+	}
+}
+
+func newTestOptions(opts ...TestOption) *testOptions {
+	o := &testOptions{
+		logTailLines: defaultLogTailLines,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Start calls the constructor, fails the test right away on error and
+// registers cleanup closing the returned value once the test is done.
+// Before closing, the last lines of the output of each container are
+// dumped to the test log if the test is failed. Containers are discovered
+// via Container(), Containers() methods or the value itself being a Container.
+// If the constructor fails because the container doesn't become ready, up
+// to 50 last lines of its output captured before the constructor closed it
+// are dumped as well.
+//
+// Usage:
+//
+//	app := docker.Start(t, func() (redis.Redis, error) {
+//		return redis.New(ctx, image)
+//	})
+func Start[T Closer](t testing.TB, fn func() (T, error), opts ...TestOption) T {
+	t.Helper()
+
+	o := newTestOptions(opts...)
+
+	v, err := fn()
+	if err != nil {
+		// Constructors close the containers on error, so the output is
+		// captured into the error before that
+		var se *startupError
+		if o.logTailLines > 0 && errors.As(err, &se) {
+			logEntries(t, se.name, se.logs, o.logTailLines)
+		}
+		t.Fatalf("error starting: %s", err)
+	}
+
+	registerCleanup(t, v, o)
+
+	return v
+}
+
+// RunContainer runs the container and registers its cleanup the same way
+// as Start does
+func RunContainer(t testing.TB, ctx context.Context, c Container, opts ...TestOption) Container {
+	t.Helper()
+
+	registerCleanup(t, c, newTestOptions(opts...))
+
+	if err := c.Run(ctx); err != nil {
+		t.Fatalf("error running container `%s`: %s", c.Name(), err)
+	}
+
+	return c
+}
+
+// RunGroup runs the group and registers its cleanup the same way as
+// Start does
+func RunGroup(t testing.TB, ctx context.Context, g Group, opts ...TestOption) Group {
+	t.Helper()
+
+	registerCleanup(t, g, newTestOptions(opts...))
+
+	if err := g.Run(ctx); err != nil {
+		t.Fatalf("error running group: %s", err)
+	}
+
+	return g
+}
+
+func registerCleanup(t testing.TB, v Closer, o *testOptions) {
+	t.Cleanup(func() {
+		// The test context is already canceled at the moment cleanup is run
+		ctx, cancel := context.WithTimeout(context.Background(), defaultStopTimeout)
+		defer cancel()
+
+		if t.Failed() && o.logTailLines > 0 {
+			for _, c := range containersOf(v) {
+				dumpLogs(ctx, t, c, o.logTailLines)
+			}
+		}
+
+		if err := v.Close(ctx); err != nil {
+			t.Errorf("error closing: %s", err)
+		}
+	})
+}
+
+func containersOf(v any) []Container {
+	switch x := v.(type) {
+	case Container:
+		return []Container{x}
+	case interface{ Containers() []Container }:
+		return x.Containers()
+	case interface{ Container() Container }:
+		return []Container{x.Container()}
+	}
+	return nil
+}
+
+func dumpLogs(ctx context.Context, t testing.TB, c Container, n int) {
+	tl, ok := c.(interface {
+		tailLogs(ctx context.Context, n int) ([]LogEntry, error)
+	})
+	if !ok {
+		return
+	}
+
+	entries, err := tl.tailLogs(ctx, n)
+	if err != nil {
+		t.Logf("error retrieving logs of container `%s`: %s", c.Name(), err)
+		return
+	}
+
+	logEntries(t, c.Name(), entries, n)
+}
+
+func logEntries(t testing.TB, name string, entries []LogEntry, n int) {
+	if len(entries) > n {
+		entries = entries[len(entries)-n:]
+	}
+
+	t.Logf("last %d lines of container `%s` output:", len(entries), name)
+	for _, e := range entries {
+		t.Logf("[%s] %s: %s", name, e.Stream, e.Line)
+	}
+}
@@ -0,0 +1,171 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	log.SetLevel(log.TraceLevel)
+}
+
+type fakeCloser struct {
+	closed int
+	err    error
+}
+
+func (c *fakeCloser) Close(context.Context) error {
+	c.closed++
+	return c.err
+}
+
+type fakeTB struct {
+	testing.TB
+
+	failed   bool
+	fatals   []string
+	errors   []string
+	logs     []string
+	cleanups []func()
+}
+
+func (tb *fakeTB) Helper() {}
+
+func (tb *fakeTB) Failed() bool {
+	return tb.failed
+}
+
+// Fatalf stops the calling goroutine as testing.T does so the helpers
+// must be called via runInGoroutine
+func (tb *fakeTB) Fatalf(format string, args ...any) {
+	tb.failed = true
+	tb.fatals = append(tb.fatals, fmt.Sprintf(format, args...))
+	runtime.Goexit()
+}
+
+func (tb *fakeTB) Errorf(format string, args ...any) {
+	tb.failed = true
+	tb.errors = append(tb.errors, fmt.Sprintf(format, args...))
+}
+
+func (tb *fakeTB) Logf(format string, args ...any) {
+	tb.logs = append(tb.logs, fmt.Sprintf(format, args...))
+}
+
+func (tb *fakeTB) Cleanup(fn func()) {
+	tb.cleanups = append(tb.cleanups, fn)
+}
+
+func (tb *fakeTB) runCleanups() {
+	for i := len(tb.cleanups) - 1; i >= 0; i-- {
+		tb.cleanups[i]()
+	}
+}
+
+func runInGoroutine(fn func()) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+	<-done
+}
+
+func TestStart(t *testing.T) {
+	r := require.New(t)
+
+	tb := &fakeTB{}
+	c := &fakeCloser{}
+
+	v := Start(tb, func() (*fakeCloser, error) {
+		return c, nil
+	})
+	r.Same(c, v)
+	r.Equal(0, c.closed)
+	r.Len(tb.cleanups, 1)
+
+	tb.runCleanups()
+	r.Equal(1, c.closed)
+	r.False(tb.failed)
+}
+
+func TestStartError(t *testing.T) {
+	r := require.New(t)
+
+	tb := &fakeTB{}
+	runInGoroutine(func() {
+		Start(tb, func() (*fakeCloser, error) {
+			return nil, errors.New("test error")
+		})
+	})
+	r.Equal([]string{"error starting: test error"}, tb.fatals)
+	r.Empty(tb.cleanups)
+}
+
+func TestStartErrorDumpsStartupLogs(t *testing.T) {
+	r := require.New(t)
+
+	tb := &fakeTB{}
+	runInGoroutine(func() {
+		Start(tb, func() (*fakeCloser, error) {
+			return nil, errors.Wrap(&startupError{
+				err:  errors.New("not ready"),
+				name: "db",
+				logs: []LogEntry{
+					{Stream: LogStreamStdout, Line: "starting"},
+					{Stream: LogStreamStderr, Line: "fatal: bad config"},
+				},
+			}, "error running container")
+		}, WithLogTailLines(1))
+	})
+	r.Equal([]string{"error starting: error running container: not ready"}, tb.fatals)
+	r.Equal([]string{
+		"last 1 lines of container `db` output:",
+		"[db] stderr: fatal: bad config",
+	}, tb.logs)
+}
+
+func TestStartCloseError(t *testing.T) {
+	r := require.New(t)
+
+	tb := &fakeTB{}
+	c := &fakeCloser{err: errors.New("test error")}
+
+	Start(tb, func() (*fakeCloser, error) {
+		return c, nil
+	}, WithLogTailLines(10))
+
+	tb.runCleanups()
+	r.Equal(1, c.closed)
+	r.Equal([]string{"error closing: test error"}, tb.errors)
+}
+
+type fakeApplication struct {
+	fakeCloser
+
+	c Container
+}
+
+func (a *fakeApplication) Container() Container {
+	return a.c
+}
+
+func TestContainersOf(t *testing.T) {
+	r := require.New(t)
+
+	c := newContainerForHostPort(t, 8080, "8080")
+
+	r.Equal([]Container{c}, containersOf(c))
+	r.Equal([]Container{c}, containersOf(&fakeApplication{c: c}))
+	r.Nil(containersOf(&fakeCloser{}))
+
+	g, err := NewGroupWithClient(nil, "test", NewApplication(c))
+	r.NoError(err)
+	r.Equal([]Container{c}, containersOf(g))
+}
@@ -0,0 +1,157 @@
+// export_images saves every image referenced in the images package and the
+// versions suites of applications into the image cache directory, so the
+// tests could run without access to registries with IMAGE_CACHE_DIR set.
+//
+// Usage:
+//
+//	go run ./tools/cmd/export_images -dir /var/cache/images [-force]
+//
+// Images missing locally are pulled first. Tarballs already present in the
+// directory are kept unless -force is passed.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+	"github.com/pkg/errors"
+
+	docker "github.com/teran/go-docker-testsuite"
+	"github.com/teran/go-docker-testsuite/tools/internal/imagerefs"
+)
+
+func main() {
+	dir := flag.String("dir", os.Getenv("IMAGE_CACHE_DIR"), "image cache directory (defaults to IMAGE_CACHE_DIR)")
+	root := flag.String("root", ".", "repository root to discover image references in")
+	force := flag.Bool("force", false, "overwrite tarballs already present in the directory")
+	flag.Parse()
+
+	if *dir == "" || flag.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "Usage: %s -dir <path> [-root <path>] [-force]\n", os.Args[0])
+		os.Exit(1)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	if err := run(ctx, *root, *dir, *force); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, root, dir string, force bool) error {
+	refs, err := imagerefs.Discover(root)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return errors.Wrapf(err, "error creating directory `%s`", dir)
+	}
+
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return errors.Wrap(err, "error creating Docker client")
+	}
+	defer func() { _ = cli.Close() }()
+
+	for _, ref := range refs {
+		name, err := docker.ImageCacheFileName(ref)
+		if err != nil {
+			return err
+		}
+
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil && !force {
+			fmt.Fprintf(os.Stderr, "%s: %s already exists, skipping\n", ref, name)
+			continue
+		}
+
+		if err := ensureImage(ctx, cli, ref); err != nil {
+			return errors.Wrapf(err, "image `%s`", ref)
+		}
+
+		if err := saveImage(ctx, cli, ref, path); err != nil {
+			return errors.Wrapf(err, "image `%s`", ref)
+		}
+
+		fmt.Fprintf(os.Stderr, "%s: saved to %s\n", ref, name)
+	}
+
+	return nil
+}
+
+// ensureImage pulls the image if it's missing locally
+func ensureImage(ctx context.Context, cli *client.Client, ref string) error {
+	_, err := cli.ImageInspect(ctx, ref)
+	if err == nil {
+		return nil
+	}
+
+	if !cerrdefs.IsNotFound(err) {
+		return errors.Wrap(err, "error inspecting image")
+	}
+
+	fmt.Fprintf(os.Stderr, "%s: pulling\n", ref)
+
+	rc, err := cli.ImagePull(ctx, ref, image.PullOptions{})
+	if err != nil {
+		return errors.Wrap(err, "error pulling image")
+	}
+	defer func() { _ = rc.Close() }()
+
+	dec := json.NewDecoder(rc)
+	for {
+		msg := struct {
+			Error string `json:"error"`
+		}{}
+		if err := dec.Decode(&msg); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return errors.Wrap(err, "error reading pull progress")
+		}
+
+		if msg.Error != "" {
+			return errors.Errorf("error pulling image: %s", msg.Error)
+		}
+	}
+}
+
+// saveImage writes `docker save` tarball of the image to the temporary file
+// renamed to path on success, so interrupted export never leaves partial
+// tarball in the cache directory
+func saveImage(ctx context.Context, cli *client.Client, ref, path string) error {
+	rc, err := cli.ImageSave(ctx, []string{ref})
+	if err != nil {
+		return errors.Wrap(err, "error saving image")
+	}
+	defer func() { _ = rc.Close() }()
+
+	fp, err := os.CreateTemp(filepath.Dir(path), ".export-*.tar")
+	if err != nil {
+		return errors.Wrap(err, "error creating temporary file")
+	}
+	defer func() { _ = os.Remove(fp.Name()) }()
+
+	if _, err := io.Copy(fp, rc); err != nil {
+		_ = fp.Close()
+		return errors.Wrap(err, "error writing image tarball")
+	}
+
+	if err := fp.Close(); err != nil {
+		return errors.Wrap(err, "error writing image tarball")
+	}
+
+	return errors.Wrap(os.Rename(fp.Name(), path), "error renaming image tarball")
+}
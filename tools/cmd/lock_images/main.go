@@ -0,0 +1,119 @@
+// lock_images resolves the digests of every image referenced in the images
+// package and the versions suites of applications and writes them to
+// images/images.lock, the digests the images are verified against after
+// pull.
+//
+// Usage:
+//
+//	go run ./tools/cmd/lock_images [-root <path>] [-check]
+//
+// The digests are resolved in the registries through the Docker daemon.
+// With -check the lock file is not written, the command fails if it's
+// out of date.
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/client"
+	"github.com/pkg/errors"
+
+	"github.com/teran/go-docker-testsuite/images"
+	"github.com/teran/go-docker-testsuite/tools/internal/imagerefs"
+)
+
+const header = `# Pinned digests of the images referenced in the images package and the
+# versions suites: ` + "`<reference> <digest>`" + ` per line. Images are verified
+# against the digests after pull.
+#
+# Generated by ` + "`go run ./tools/cmd/lock_images`" + `, do not edit manually.
+`
+
+func main() {
+	root := flag.String("root", ".", "repository root to discover image references in")
+	check := flag.Bool("check", false, "fail if the lock file is out of date instead of writing it")
+	flag.Parse()
+
+	if flag.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "Usage: %s [-root <path>] [-check]\n", os.Args[0])
+		os.Exit(1)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	if err := run(ctx, *root, *check); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, root string, check bool) error {
+	refs, err := imagerefs.Discover(root)
+	if err != nil {
+		return err
+	}
+
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return errors.Wrap(err, "error creating Docker client")
+	}
+	defer func() { _ = cli.Close() }()
+
+	buf := &bytes.Buffer{}
+	buf.WriteString(header)
+	buf.WriteString("\n")
+
+	path := filepath.Join(root, "images", images.LockFile)
+	current, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "error reading `%s`", path)
+	}
+
+	locked := images.ParseLock(string(current))
+	stale := []string{}
+
+	for _, ref := range refs {
+		info, err := cli.DistributionInspect(ctx, ref, "")
+		if err != nil {
+			return errors.Wrapf(err, "error resolving digest of `%s`", ref)
+		}
+
+		d := info.Descriptor.Digest.String()
+		switch locked[ref] {
+		case d:
+		case "":
+			stale = append(stale, fmt.Sprintf("%s: not pinned, %s", ref, d))
+		default:
+			stale = append(stale, fmt.Sprintf("%s: pinned %s, got %s", ref, locked[ref], d))
+		}
+
+		fmt.Fprintf(os.Stderr, "%s: %s\n", ref, d)
+		fmt.Fprintf(buf, "%s %s\n", ref, d)
+	}
+
+	for ref := range locked {
+		if !slices.Contains(refs, ref) {
+			stale = append(stale, fmt.Sprintf("%s: pinned but not referenced", ref))
+		}
+	}
+
+	if check {
+		if len(stale) > 0 {
+			sort.Strings(stale)
+			return errors.Errorf("`%s` is out of date, run `go run ./tools/cmd/lock_images`:\n%s", path, strings.Join(stale, "\n"))
+		}
+		return nil
+	}
+
+	return errors.Wrapf(os.WriteFile(path, buf.Bytes(), 0o644), "error writing `%s`", path)
+}
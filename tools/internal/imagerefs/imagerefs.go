@@ -0,0 +1,114 @@
+// Package imagerefs discovers the image references used across the
+// repository: the images package and the versions suites of applications.
+package imagerefs
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/distribution/reference"
+	"github.com/pkg/errors"
+)
+
+// Discover walks the images package and applications/*/versions/** under
+// root and returns the sorted unique image references found in string
+// literals of Go files (tests included). Only fully-qualified references
+// (with the registry domain and the tag or digest) are taken into account.
+func Discover(root string) ([]string, error) {
+	seen := map[string]struct{}{}
+
+	dirs := []string{filepath.Join(root, "images")}
+	versions, err := filepath.Glob(filepath.Join(root, "applications", "*", "versions"))
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing versions suites")
+	}
+	dirs = append(dirs, versions...)
+
+	for _, dir := range dirs {
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if d.IsDir() || filepath.Ext(path) != ".go" {
+				return nil
+			}
+
+			refs, err := fileReferences(path)
+			if err != nil {
+				return err
+			}
+
+			for _, ref := range refs {
+				seen[ref] = struct{}{}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "error walking `%s`", dir)
+		}
+	}
+
+	refs := make([]string, 0, len(seen))
+	for ref := range seen {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+
+	return refs, nil
+}
+
+// fileReferences returns the image references from the string literals
+// of the Go file
+func fileReferences(path string) ([]string, error) {
+	f, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error parsing `%s`", path)
+	}
+
+	refs := []string{}
+	ast.Inspect(f, func(n ast.Node) bool {
+		lit, ok := n.(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return true
+		}
+
+		s, err := strconv.Unquote(lit.Value)
+		if err != nil {
+			return true
+		}
+
+		if IsReference(s) {
+			refs = append(refs, s)
+		}
+		return true
+	})
+
+	return refs, nil
+}
+
+// IsReference reports whether the string is fully-qualified image reference,
+// i.e. "index.docker.io/library/postgres:16.3"
+func IsReference(s string) bool {
+	domain, _, ok := strings.Cut(s, "/")
+	if !ok || !strings.ContainsAny(domain, ".:") {
+		return false
+	}
+
+	named, err := reference.ParseNormalizedNamed(s)
+	if err != nil {
+		return false
+	}
+
+	switch named.(type) {
+	case reference.Tagged, reference.Digested:
+		return true
+	}
+	return false
+}
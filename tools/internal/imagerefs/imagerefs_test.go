@@ -0,0 +1,42 @@
+package imagerefs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsReference(t *testing.T) {
+	tcs := []struct {
+		in       string
+		expected bool
+	}{
+		{in: "index.docker.io/library/postgres:16.3", expected: true},
+		{in: "ghcr.io/teran/echo-grpc-server:latest", expected: true},
+		{in: "localhost:5000/app:v1", expected: true},
+		{in: "quay.io/app@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", expected: true},
+		{in: "index.docker.io/library/postgres", expected: false},
+		{in: "postgres:16.3", expected: false},
+		{in: "library/postgres:16.3", expected: false},
+		{in: "some text", expected: false},
+		{in: "", expected: false},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.in, func(t *testing.T) {
+			r := require.New(t)
+			r.Equal(tc.expected, IsReference(tc.in))
+		})
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	r := require.New(t)
+
+	refs, err := Discover("../../..")
+	r.NoError(err)
+	r.Contains(refs, "index.docker.io/library/postgres:16.3")
+	r.Contains(refs, "index.docker.io/apache/kafka:4.1.2")
+	r.Contains(refs, "ghcr.io/teran/echo-grpc-server:latest")
+	r.IsIncreasing(refs)
+}
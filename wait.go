@@ -0,0 +1,139 @@
+package docker
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultPollInterval = 500 * time.Millisecond
+)
+
+// WaitStrategy blocks until the container is ready to serve requests
+type WaitStrategy interface {
+	WaitUntilReady(ctx context.Context, c Container) error
+}
+
+// WaitStrategyFunc allows to use ordinary function as WaitStrategy
+type WaitStrategyFunc func(ctx context.Context, c Container) error
+
+// WaitUntilReady calls the function itself
+func (f WaitStrategyFunc) WaitUntilReady(ctx context.Context, c Container) error {
+	return f(ctx, c)
+}
+
+// ForAll waits for all of the strategies one by one in the order they are passed
+func ForAll(strategies ...WaitStrategy) WaitStrategy {
+	return WaitStrategyFunc(func(ctx context.Context, c Container) error {
+		for _, ws := range strategies {
+			if err := ws.WaitUntilReady(ctx, c); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ForAny waits for the strategies concurrently and returns as soon as
+// any of them succeeded. Error is returned only when all of them failed.
+func ForAny(strategies ...WaitStrategy) WaitStrategy {
+	return WaitStrategyFunc(func(ctx context.Context, c Container) error {
+		if len(strategies) == 0 {
+			return nil
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		errCh := make(chan error, len(strategies))
+		for _, ws := range strategies {
+			go func(ws WaitStrategy) {
+				errCh <- ws.WaitUntilReady(ctx, c)
+			}(ws)
+		}
+
+		msgs := []string{}
+		for range strategies {
+			err := <-errCh
+			if err == nil {
+				return nil
+			}
+			msgs = append(msgs, err.Error())
+		}
+
+		return errors.Errorf("none of wait strategies succeeded: [%s]", strings.Join(msgs, "; "))
+	})
+}
+
+// WithTimeout limits the time the strategy is allowed to wait
+func WithTimeout(ws WaitStrategy, timeout time.Duration) WaitStrategy {
+	return WaitStrategyFunc(func(ctx context.Context, c Container) error {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		return ws.WaitUntilReady(ctx, c)
+	})
+}
+
+type pollIntervalKey struct{}
+
+// WithPollInterval sets the interval between checks for the polling
+// strategies (including nested ones)
+func WithPollInterval(ws WaitStrategy, interval time.Duration) WaitStrategy {
+	return WaitStrategyFunc(func(ctx context.Context, c Container) error {
+		return ws.WaitUntilReady(context.WithValue(ctx, pollIntervalKey{}, interval), c)
+	})
+}
+
+// ForCheck polls the check until it succeeds
+func ForCheck(check func(ctx context.Context, c Container) error) WaitStrategy {
+	return WaitStrategyFunc(func(ctx context.Context, c Container) error {
+		return poll(ctx, func(ctx context.Context) error {
+			return check(ctx, c)
+		})
+	})
+}
+
+// permanentError stops polling immediately
+type permanentError struct {
+	error
+}
+
+func (e permanentError) Unwrap() error {
+	return e.error
+}
+
+// poll calls fn until it succeeds, returns permanentError or context is done
+func poll(ctx context.Context, fn func(ctx context.Context) error) error {
+	interval := defaultPollInterval
+	if v, ok := ctx.Value(pollIntervalKey{}).(time.Duration); ok && v > 0 {
+		interval = v
+	}
+
+	for {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+
+		var pe permanentError
+		if errors.As(err, &pe) {
+			return pe.error
+		}
+
+		log.WithFields(log.Fields{
+			"error":    err.Error(),
+			"interval": interval,
+		}).Trace("check is not passed yet, waiting ...")
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(ctx.Err(), "last error: %s", err.Error())
+		case <-time.After(interval):
+		}
+	}
+}
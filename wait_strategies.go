@@ -0,0 +1,217 @@
+package docker
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	portProbeTimeout = 200 * time.Millisecond
+)
+
+// ForLog waits for the container output line matched by Matcher
+func ForLog(m Matcher) WaitStrategy {
+	return WaitStrategyFunc(func(ctx context.Context, c Container) error {
+		return c.AwaitOutput(ctx, m)
+	})
+}
+
+// ForListeningPort waits for the TCP port to accept connections on the host side.
+//
+// Docker userland proxy accepts connections even when nothing listens inside
+// the container and closes them immediately, so the connection is
+// considered successful only when it's not closed by the remote side
+// right away.
+func ForListeningPort(proto Protocol, port uint16) WaitStrategy {
+	return WaitStrategyFunc(func(ctx context.Context, c Container) error {
+		if proto != ProtoTCP {
+			return errors.Errorf("listening port check is not supported for `%s`", proto)
+		}
+
+		hp, err := c.URL(proto, port)
+		if err != nil {
+			return err
+		}
+
+		return poll(ctx, func(ctx context.Context) error {
+			return probePort(ctx, hp)
+		})
+	})
+}
+
+func probePort(ctx context.Context, hp *HostPort) error {
+	d := net.Dialer{}
+	conn, err := d.DialContext(ctx, ProtoTCP.String(), hp.String())
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+
+	if err := conn.SetReadDeadline(time.Now().Add(portProbeTimeout)); err != nil {
+		return err
+	}
+
+	_, err = conn.Read(make([]byte, 1))
+	if err == nil || errors.Is(err, os.ErrDeadlineExceeded) {
+		return nil
+	}
+	return errors.Wrap(err, "connection closed by remote side")
+}
+
+// HTTPWaitStrategy waits for HTTP endpoint of the container to respond
+// with expected status code and body
+type HTTPWaitStrategy struct {
+	port        uint16
+	path        string
+	statusCode  int
+	bodyMatcher Matcher
+	username    string
+	password    string
+}
+
+// ForHTTP waits for the HTTP endpoint at the path on the container port
+// to respond with 2xx status code
+func ForHTTP(port uint16, path string) *HTTPWaitStrategy {
+	return &HTTPWaitStrategy{
+		port: port,
+		path: path,
+	}
+}
+
+// WithStatusCode sets exact status code to expect
+func (s *HTTPWaitStrategy) WithStatusCode(code int) *HTTPWaitStrategy {
+	s.statusCode = code
+	return s
+}
+
+// WithBody sets Matcher the response body must match
+func (s *HTTPWaitStrategy) WithBody(m Matcher) *HTTPWaitStrategy {
+	s.bodyMatcher = m
+	return s
+}
+
+// WithBasicAuth sets credentials to pass along with the request
+func (s *HTTPWaitStrategy) WithBasicAuth(username, password string) *HTTPWaitStrategy {
+	s.username = username
+	s.password = password
+	return s
+}
+
+// WaitUntilReady implements WaitStrategy
+func (s *HTTPWaitStrategy) WaitUntilReady(ctx context.Context, c Container) error {
+	hp, err := c.URL(ProtoTCP, s.port)
+	if err != nil {
+		return err
+	}
+
+	u := fmt.Sprintf("http://%s%s", hp.String(), s.path)
+
+	return poll(ctx, func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return err
+		}
+
+		if s.username != "" || s.password != "" {
+			req.SetBasicAuth(s.username, s.password)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = resp.Body.Close() }()
+
+		if s.statusCode != 0 && resp.StatusCode != s.statusCode {
+			return errors.Errorf("unexpected status code: expected %d, got %d", s.statusCode, resp.StatusCode)
+		}
+
+		if s.statusCode == 0 && (resp.StatusCode < 200 || resp.StatusCode > 299) {
+			return errors.Errorf("unexpected status code: %d", resp.StatusCode)
+		}
+
+		if s.bodyMatcher != nil {
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				return err
+			}
+
+			if !s.bodyMatcher(string(body)) {
+				return errors.New("response body is not matched")
+			}
+		}
+
+		return nil
+	})
+}
+
+// ExecWaitStrategy waits for the command executed in the container to exit
+// with expected exit code
+type ExecWaitStrategy struct {
+	cmd      []string
+	exitCode int
+}
+
+// ForExec waits for the command executed inside the container to exit with 0
+func ForExec(cmd ...string) *ExecWaitStrategy {
+	return &ExecWaitStrategy{
+		cmd: cmd,
+	}
+}
+
+// WithExitCode sets the exit code to expect
+func (s *ExecWaitStrategy) WithExitCode(code int) *ExecWaitStrategy {
+	s.exitCode = code
+	return s
+}
+
+// WaitUntilReady implements WaitStrategy
+func (s *ExecWaitStrategy) WaitUntilReady(ctx context.Context, c Container) error {
+	return poll(ctx, func(ctx context.Context) error {
+		res, err := c.Exec(ctx, s.cmd, nil)
+		if err != nil {
+			return err
+		}
+
+		if res.ExitCode != s.exitCode {
+			return errors.Errorf("command exited with code %d: %s", res.ExitCode, string(res.Stderr))
+		}
+		return nil
+	})
+}
+
+// ForSQL waits for the database to respond to ping. The driver must be
+// registered by the caller, dsn is called with the external address of the port.
+func ForSQL(driverName string, port uint16, dsn func(hp *HostPort) string) WaitStrategy {
+	return WaitStrategyFunc(func(ctx context.Context, c Container) error {
+		hp, err := c.URL(ProtoTCP, port)
+		if err != nil {
+			return err
+		}
+
+		db, err := sql.Open(driverName, dsn(hp))
+		if err != nil {
+			return errors.Wrap(err, "error opening database connection")
+		}
+		defer func() { _ = db.Close() }()
+
+		return poll(ctx, func(ctx context.Context) error {
+			return db.PingContext(ctx)
+		})
+	})
+}
+
+// ForHealthy waits for Docker to report the container as healthy
+func ForHealthy() WaitStrategy {
+	return WaitStrategyFunc(func(ctx context.Context, c Container) error {
+		return c.AwaitHealthy(ctx)
+	})
+}
@@ -0,0 +1,204 @@
+package docker
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	log.SetLevel(log.TraceLevel)
+}
+
+// newContainerForHostPort creates the container (not running) which maps
+// the port to the port listened on the local host
+func newContainerForHostPort(t *testing.T, port uint16, hostPort string) Container {
+	t.Setenv("DOCKER_HOST", "")
+
+	hp, err := strconv.ParseUint(hostPort, 10, 16)
+	require.NoError(t, err)
+
+	c, err := NewContainerWithClient(nil, "test", "image:test", nil, NewEnvironment(),
+		NewPortBindingsWithPortAllocator(func(proto Protocol, port uint16) (string, uint16, []string, error) {
+			return strconv.FormatUint(uint64(port), 10) + "/" + proto.String(), uint16(hp), []string{}, nil
+		}).PortDNAT(ProtoTCP, port),
+	)
+	require.NoError(t, err)
+	return c
+}
+
+func TestWaitForAll(t *testing.T) {
+	r := require.New(t)
+
+	calls := []int{}
+	ws := ForAll(
+		WaitStrategyFunc(func(ctx context.Context, c Container) error {
+			calls = append(calls, 1)
+			return nil
+		}),
+		WaitStrategyFunc(func(ctx context.Context, c Container) error {
+			calls = append(calls, 2)
+			return errors.New("test error")
+		}),
+		WaitStrategyFunc(func(ctx context.Context, c Container) error {
+			calls = append(calls, 3)
+			return nil
+		}),
+	)
+
+	err := ws.WaitUntilReady(t.Context(), nil)
+	r.Error(err)
+	r.Equal("test error", err.Error())
+	r.Equal([]int{1, 2}, calls)
+}
+
+func TestWaitForAny(t *testing.T) {
+	r := require.New(t)
+
+	blocking := WaitStrategyFunc(func(ctx context.Context, c Container) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	failing := WaitStrategyFunc(func(ctx context.Context, c Container) error {
+		return errors.New("test error")
+	})
+	passing := WaitStrategyFunc(func(ctx context.Context, c Container) error {
+		return nil
+	})
+
+	err := ForAny(blocking, failing, passing).WaitUntilReady(t.Context(), nil)
+	r.NoError(err)
+
+	err = ForAny(failing, failing).WaitUntilReady(t.Context(), nil)
+	r.Error(err)
+	r.Equal("none of wait strategies succeeded: [test error; test error]", err.Error())
+}
+
+func TestWaitWithTimeoutAndPollInterval(t *testing.T) {
+	r := require.New(t)
+
+	var calls atomic.Int32
+	ws := WithTimeout(WithPollInterval(ForCheck(func(ctx context.Context, c Container) error {
+		calls.Add(1)
+		return errors.New("not ready")
+	}), 10*time.Millisecond), 200*time.Millisecond)
+
+	err := ws.WaitUntilReady(t.Context(), nil)
+	r.ErrorIs(err, context.DeadlineExceeded)
+	r.Contains(err.Error(), "last error: not ready")
+	r.Greater(calls.Load(), int32(5))
+}
+
+func TestWaitForCheckPermanentError(t *testing.T) {
+	r := require.New(t)
+
+	var calls atomic.Int32
+	err := ForCheck(func(ctx context.Context, c Container) error {
+		calls.Add(1)
+		return permanentError{ErrContainerExited}
+	}).WaitUntilReady(t.Context(), nil)
+	r.ErrorIs(err, ErrContainerExited)
+	r.Equal(int32(1), calls.Load())
+}
+
+func TestAwaitOutput(t *testing.T) {
+	r := require.New(t)
+
+	err := awaitOutput(t.Context(), strings.NewReader("starting\nready\n"), NewSubstringMatcher("ready"))
+	r.NoError(err)
+
+	// The output ends once the container exits
+	err = awaitOutput(t.Context(), strings.NewReader("starting\npanic: boom\n"), NewSubstringMatcher("ready"))
+	r.ErrorIs(err, ErrContainerExited)
+
+	err = awaitOutput(t.Context(), strings.NewReader(""), NewSubstringMatcher("ready"))
+	r.ErrorIs(err, ErrContainerExited)
+}
+
+func TestWaitForHTTP(t *testing.T) {
+	r := require.New(t)
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		u, p, ok := req.BasicAuth()
+		if !ok || u != "user" || p != "password" || req.URL.Path != "/health" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer srv.Close()
+
+	_, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	r.NoError(err)
+
+	c := newContainerForHostPort(t, 8080, port)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+
+	err = WithPollInterval(
+		ForHTTP(8080, "/health").
+			WithStatusCode(http.StatusOK).
+			WithBasicAuth("user", "password").
+			WithBody(NewSubstringMatcher(`"ok"`)),
+		10*time.Millisecond,
+	).WaitUntilReady(ctx, c)
+	r.NoError(err)
+	r.Equal(int32(3), calls.Load())
+}
+
+func TestWaitForListeningPort(t *testing.T) {
+	r := require.New(t)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	r.NoError(err)
+	defer func() { _ = ln.Close() }()
+
+	var accepted atomic.Int32
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			// Emulate docker userland proxy: close first connections right away
+			if accepted.Add(1) < 3 {
+				_ = conn.Close()
+				continue
+			}
+			defer func() { _ = conn.Close() }()
+		}
+	}()
+
+	_, port, err := net.SplitHostPort(ln.Addr().String())
+	r.NoError(err)
+
+	c := newContainerForHostPort(t, 5432, port)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+
+	err = WithPollInterval(ForListeningPort(ProtoTCP, 5432), 10*time.Millisecond).WaitUntilReady(ctx, c)
+	r.NoError(err)
+	r.Equal(int32(3), accepted.Load())
+
+	err = ForListeningPort(ProtoUDP, 5432).WaitUntilReady(ctx, c)
+	r.Error(err)
+}